@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
 	"os"
 	"slices"
@@ -9,11 +10,33 @@ import (
 	"strings"
 )
 
+// The allowed range for the absolute gap between consecutive levels in a
+// report, inclusive.
+type bounds struct {
+	lo, hi int
+}
+
+var (
+	minGap     = flag.Int("min", 1, "smallest allowed gap between consecutive levels")
+	maxGap     = flag.Int("max", 3, "largest allowed gap between consecutive levels")
+	tolerance  = flag.Int("k", 1, "number of levels that may be removed to make a report safe, for part 2")
+	showRepair = flag.Bool("repair", false, "print which levels to remove to make each report safe")
+)
+
 func main() {
+	flag.Parse()
+
 	input := readInput()
+	b := bounds{*minGap, *maxGap}
+
+	fmt.Println("Part 1:", part1(input, b))
+	fmt.Println("Part 2:", part2(input, b, *tolerance))
 
-	fmt.Println("Part 1:", part1(input))
-	fmt.Println("Part 2:", part2(input))
+	if *showRepair {
+		for i, report := range input {
+			fmt.Printf("%d: %s\n", i+1, describeRepair(report, b, *tolerance))
+		}
+	}
 }
 
 func readInput() (input [][]int) {
@@ -33,9 +56,9 @@ func readInput() (input [][]int) {
 	return
 }
 
-func part1(input [][]int) (safe int) {
+func part1(input [][]int, b bounds) (safe int) {
 	for _, row := range input {
-		if isSafe(row) {
+		if isSafe(row, b) {
 			safe++
 		}
 	}
@@ -43,9 +66,9 @@ func part1(input [][]int) (safe int) {
 	return
 }
 
-func part2(input [][]int) (almostSafe int) {
+func part2(input [][]int, b bounds, k int) (almostSafe int) {
 	for _, row := range input {
-		if isAlmostSafe(row) {
+		if _, ok := repair(row, b, k); ok {
 			almostSafe++
 		}
 	}
@@ -54,57 +77,149 @@ func part2(input [][]int) (almostSafe int) {
 }
 
 // A report is considered safe if it is strictly monotonic and the absolute gap
-// between consecutive elements does not exceed 3
-func isSafe(report []int) bool {
-	isIncreasing := slices.IsSortedFunc(report, func(a, b int) int { return a - b })
-	isDecreasing := slices.IsSortedFunc(report, func(a, b int) int { return b - a })
-
-	var sign int
-
-	// If these values are the same the slice is either not sorted, or it
-	// contains a run of the same number. In either case, it does not match the
-	// safety criteria.
-	if isIncreasing == isDecreasing {
-		return false
-	} else if isIncreasing {
-		sign = 1
-	} else {
-		sign = -1
+// between consecutive elements is within bounds `b`.
+func isSafe(report []int, b bounds) bool {
+	_, ok := repair(report, b, 0)
+	return ok
+}
+
+// Find the fewest levels that need to be removed from `report` to make it
+// safe, as long as there are at most `k` of them. Returns the indices of the
+// levels to remove, in increasing order, and whether that was possible.
+//
+// This is a search for the longest subsequence of levels that is safe, in
+// either direction. Removing more than `k` consecutive levels would exceed the
+// budget, so each level only needs to be compared with the `k + 1` levels
+// before it, which keeps the search linear for fixed `k`.
+func repair(report []int, b bounds, k int) (removed []int, ok bool) {
+	if len(report) <= 1 {
+		return nil, true
 	}
 
-	for i := 1; i < len(report); i++ {
-		delta := sign * (report[i] - report[i-1])
-		if delta < 1 || 3 < delta {
-			return false
+	best, bestKept := k+1, []int(nil)
+	for _, sign := range []int{1, -1} {
+		if n, kept := longestSafe(report, b, k, sign); n < best {
+			best, bestKept = n, kept
 		}
 	}
 
-	return true
+	if best > k {
+		return nil, false
+	}
+
+	j := 0
+	for i := range report {
+		if j < len(bestKept) && bestKept[j] == i {
+			j++
+		} else {
+			removed = append(removed, i)
+		}
+	}
+
+	return removed, true
 }
 
-// A report is considered almost safe if it is safe, or would be considered
-// safe after removing one of its elements.
-//
-// It's possible to do this in linear time, because the effect of removing an
-// element is local to its neighbourhood, but we're going to do the naive thing
-// and create copies of the report with elements removed.
-func isAlmostSafe(report []int) bool {
-	if isSafe(report) {
-		return true
+// Find the fewest removals needed to make `report` safe when it is moving in
+// direction `sign`, only considering gaps of up to `k` consecutive removals.
+// Returns the number of removals and the indices of the levels that are kept.
+func longestSafe(report []int, b bounds, k, sign int) (int, []int) {
+	n := len(report)
+
+	// drop[i] is the fewest levels dropped before `i`, in a safe prefix that
+	// ends by keeping `i`, and prev[i] is the level kept before `i` in that
+	// prefix (or -1).
+	drop := make([]int, n)
+	prev := make([]int, n)
+	for i := range report {
+		drop[i], prev[i] = i, -1
+		for j := max(0, i-k-1); j < i; j++ {
+			if d := drop[j] + i - j - 1; d < drop[i] && b.allows(sign*(report[i]-report[j])) {
+				drop[i], prev[i] = d, j
+			}
+		}
 	}
 
-	for i := 0; i < len(report); i++ {
-		if isSafe(remove(report, i)) {
-			return true
+	last := n - 1
+	for i := range report {
+		if drop[i]+n-1-i < drop[last]+n-1-last {
+			last = i
 		}
 	}
 
-	return false
+	var kept []int
+	for i := last; i >= 0; i = prev[i] {
+		kept = append(kept, i)
+	}
+
+	slices.Reverse(kept)
+
+	return drop[last] + n - 1 - last, kept
+}
+
+// Returns the indices of all the levels in `report` that could be removed on
+// their own to make it safe. A level can be removed if the levels before it
+// are safe, the levels after it are safe, and its neighbours are compatible
+// with each other, which can all be checked with one pass in each direction.
+func removable(report []int, b bounds) (indices []int) {
+	n := len(report)
+	if n <= 2 {
+		for i := range report {
+			indices = append(indices, i)
+		}
+		return
+	}
+
+	can := make([]bool, n)
+	for _, sign := range []int{1, -1} {
+		step := func(i, j int) bool {
+			return b.allows(sign * (report[j] - report[i]))
+		}
+
+		// prefix[i] is whether report[:i+1] is safe, and suffix[i] is whether
+		// report[i:] is safe.
+		prefix, suffix := make([]bool, n), make([]bool, n)
+		prefix[0], suffix[n-1] = true, true
+		for i := 1; i < n; i++ {
+			prefix[i] = prefix[i-1] && step(i-1, i)
+		}
+
+		for i := n - 2; i >= 0; i-- {
+			suffix[i] = suffix[i+1] && step(i, i+1)
+		}
+
+		can[0] = can[0] || suffix[1]
+		can[n-1] = can[n-1] || prefix[n-2]
+		for i := 1; i < n-1; i++ {
+			can[i] = can[i] || (prefix[i-1] && suffix[i+1] && step(i-1, i+1))
+		}
+	}
+
+	for i, ok := range can {
+		if ok {
+			indices = append(indices, i)
+		}
+	}
+
+	return
+}
+
+// Describe whether `report` is safe, or how to make it safe by removing at
+// most `k` levels. When a single level needs to be removed, every level that
+// could be removed instead is listed too.
+func describeRepair(report []int, b bounds, k int) string {
+	removed, ok := repair(report, b, k)
+	switch {
+	case !ok:
+		return "unsafe"
+	case len(removed) == 0:
+		return "safe"
+	case len(removed) == 1:
+		return fmt.Sprintf("safe after removing %v, or any one of %v", removed, removable(report, b))
+	default:
+		return fmt.Sprintf("safe after removing %v", removed)
+	}
 }
 
-func remove(xs []int, i int) []int {
-	dst := make([]int, len(xs)-1)
-	copy(dst, xs[:i])
-	copy(dst[i:], xs[i+1:])
-	return dst
+func (b bounds) allows(delta int) bool {
+	return b.lo <= delta && delta <= b.hi
 }
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+var example = [][]int{
+	{7, 6, 4, 2, 1},
+	{1, 2, 7, 8, 9},
+	{9, 7, 6, 2, 1},
+	{1, 3, 2, 4, 5},
+	{8, 6, 4, 4, 1},
+	{1, 3, 6, 7, 9},
+}
+
+var puzzle = bounds{1, 3}
+
+// Check safety directly from the definition.
+func naiveSafe(report []int, b bounds) bool {
+	for _, sign := range []int{1, -1} {
+		ok := true
+		for i := 1; i < len(report); i++ {
+			ok = ok && b.allows(sign*(report[i]-report[i-1]))
+		}
+
+		if ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Check whether `report` can be made safe by removing at most `k` levels, by
+// trying every combination of removals.
+func naiveRepairable(report []int, b bounds, k int) bool {
+	if naiveSafe(report, b) {
+		return true
+	}
+
+	if k == 0 {
+		return false
+	}
+
+	for i := range report {
+		if naiveRepairable(slices.Delete(slices.Clone(report), i, i+1), b, k-1) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestExample(t *testing.T) {
+	if actual := part1(example, puzzle); actual != 2 {
+		t.Errorf("part 1: expected 2, got %d", actual)
+	}
+
+	if actual := part2(example, puzzle, 1); actual != 4 {
+		t.Errorf("part 2: expected 4, got %d", actual)
+	}
+}
+
+func TestRepairExample(t *testing.T) {
+	if removed, ok := repair(example[3], puzzle, 1); !ok || !slices.Equal(removed, []int{2}) {
+		t.Errorf("expected to remove [2], got %v (ok: %v)", removed, ok)
+	}
+
+	if actual := removable(example[3], puzzle); !slices.Equal(actual, []int{1, 2}) {
+		t.Errorf("expected [1 2] to be removable, got %v", actual)
+	}
+
+	if actual := removable(example[1], puzzle); len(actual) != 0 {
+		t.Errorf("expected nothing to be removable, got %v", actual)
+	}
+}
+
+func TestDescribeRepair(t *testing.T) {
+	for _, tc := range []struct {
+		report []int
+		k      int
+		expect string
+	}{
+		{example[0], 1, "safe"},
+		{example[1], 1, "unsafe"},
+		{example[3], 1, "safe after removing [2], or any one of [1 2]"},
+		{[]int{1, 2, 9, 10, 3, 4}, 2, "safe after removing [2 3]"},
+	} {
+		if actual := describeRepair(tc.report, puzzle, tc.k); actual != tc.expect {
+			t.Errorf("%v (k = %d): expected %q, got %q", tc.report, tc.k, tc.expect, actual)
+		}
+	}
+}
+
+func TestRepairMatchesNaive(t *testing.T) {
+	r := rand.New(rand.NewSource(0))
+	for i := 0; i < 2000; i++ {
+		report := make([]int, 3+r.Intn(6))
+		report[0] = r.Intn(20)
+		for j := 1; j < len(report); j++ {
+			report[j] = report[j-1] + r.Intn(9) - 3
+		}
+
+		b := bounds{r.Intn(2), 1 + r.Intn(4)}
+		for k := 0; k <= 2; k++ {
+			removed, ok := repair(report, b, k)
+			if expect := naiveRepairable(report, b, k); ok != expect {
+				t.Fatalf("%v, %+v, k = %d: expected %v, got %v", report, b, k, expect, ok)
+			}
+
+			if !ok {
+				continue
+			}
+
+			if len(removed) > k {
+				t.Fatalf("%v, k = %d: removed too many levels %v", report, k, removed)
+			}
+
+			kept := slices.Clone(report)
+			for j := len(removed) - 1; j >= 0; j-- {
+				kept = slices.Delete(kept, removed[j], removed[j]+1)
+			}
+
+			if !naiveSafe(kept, b) {
+				t.Fatalf("%v, k = %d: removing %v leaves unsafe %v", report, k, removed, kept)
+			}
+		}
+
+		for j := range report {
+			expect := naiveSafe(slices.Delete(slices.Clone(report), j, j+1), b)
+			if actual := slices.Contains(removable(report, b), j); actual != expect {
+				t.Fatalf("%v, %+v: expected removable(%d) = %v, got %v", report, b, j, expect, actual)
+			}
+		}
+	}
+}
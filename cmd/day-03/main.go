@@ -1,75 +1,248 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"flag"
 	"fmt"
 	"io"
 	"os"
-	"regexp"
-	"strconv"
 )
 
-var reMul = regexp.MustCompile(`mul\((\d{1,3}),(\d{1,3})\)`)
-var reCmd = regexp.MustCompile(`(do)\(\)|(don't)\(\)|(mul)\((\d{1,3}),(\d{1,3})\)`)
+// An instruction that can appear in corrupted memory, written as its name
+// followed by `arity` comma-separated numbers of 1 to 3 digits in parentheses,
+// e.g. `mul(12,345)`.
+type instruction struct {
+	name  string
+	arity int
+
+	// Gated instructions are skipped while the machine is disabled.
+	gated bool
+
+	exec func(m *machine, args []int)
+}
+
+// The state that instructions act on.
+type machine struct {
+	enabled bool
+	total   int
+}
+
+// Scans corrupted memory for a registered set of instructions, and executes
+// them as they are found.
+type interpreter struct {
+	instructions []instruction
+	maxLen       int
+}
+
+// An instruction that was executed, found `offset` bytes into memory.
+type execution struct {
+	offset int64
+	name   string
+	args   []int
+}
+
+// A range of memory, from `start` (inclusive) to `end` (exclusive), over which
+// gated instructions were either enabled or disabled.
+type span struct {
+	start, end int64
+	enabled    bool
+}
+
+// Callbacks for following along with the interpreter as it runs. Either may
+// be `nil`.
+type observer struct {
+	exec func(execution)
+	span func(span)
+}
+
+const MAX_DIGITS = 3
+
+var trace = flag.Bool("trace", false, "print every instruction executed in part 2, and the spans over which instructions were enabled")
 
 func main() {
-	inputBytes, err := io.ReadAll(os.Stdin)
+	flag.Parse()
+
+	var obs observer
+	if *trace {
+		obs.exec = func(e execution) {
+			fmt.Printf("%d: %v\n", e.offset, e)
+		}
+
+		obs.span = func(s span) {
+			state := "disabled"
+			if s.enabled {
+				state = "enabled"
+			}
+			fmt.Printf("%d-%d: %s\n", s.start, s.end, state)
+		}
+	}
+
+	// Both parts read the input as it streams in: part 1 reads from stdin
+	// directly and copies what it reads into a pipe for part 2.
+	pr, pw := io.Pipe()
+	part2Total := make(chan int)
+	go func() {
+		total, err := part2(pr, obs)
+		pr.CloseWithError(err)
+		part2Total <- total
+	}()
+
+	part1Total, err := part1(io.TeeReader(os.Stdin, pw))
+	pw.CloseWithError(err)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error reading input: %v\n", err)
 		os.Exit(1)
 	}
 
-	input := string(inputBytes)
-	fmt.Println("Part 1:", part1(input))
-	fmt.Println("Part 2:", part2(input))
+	total := <-part2Total
+	fmt.Println("Part 1:", part1Total)
+	fmt.Println("Part 2:", total)
+}
+
+func part1(r io.Reader) (int, error) {
+	in := newInterpreter()
+	in.register("mul", 2, false, mul)
+	return in.run(r, observer{})
 }
 
-func part1(input string) (total int) {
-	for _, match := range reMul.FindAllStringSubmatch(input, -1) {
-		a, b := match[1], match[2]
+func part2(r io.Reader, obs observer) (int, error) {
+	in := newInterpreter()
+	in.register("mul", 2, true, mul)
+	in.register("do", 0, false, func(m *machine, _ []int) { m.enabled = true })
+	in.register("don't", 0, false, func(m *machine, _ []int) { m.enabled = false })
+	return in.run(r, obs)
+}
 
-		ai, err := strconv.Atoi(a)
-		if err != nil {
-			continue
+func mul(m *machine, args []int) {
+	m.total += args[0] * args[1]
+}
+
+func newInterpreter() *interpreter {
+	return &interpreter{}
+}
+
+// Teach the interpreter to recognise a new instruction. Instructions are
+// tried in the order they were registered.
+func (in *interpreter) register(name string, arity int, gated bool, exec func(*machine, []int)) {
+	in.instructions = append(in.instructions, instruction{name, arity, gated, exec})
+
+	// name + "(" + (digits + ",") * arity + ")", with one less comma than
+	// arguments, but always room for the closing parenthesis.
+	in.maxLen = max(in.maxLen, len(name)+2+arity*(MAX_DIGITS+1))
+}
+
+// Scan memory from `r`, executing every instruction found, and return the
+// final total. Memory is read through a buffer that only needs to be large
+// enough to hold the longest possible instruction, so it never needs to be
+// held in memory all at once.
+func (in *interpreter) run(r io.Reader, obs observer) (int, error) {
+	br := bufio.NewReaderSize(r, max(4096, in.maxLen))
+	m := machine{enabled: true}
+
+	var offset, spanStart int64
+	for {
+		buf, err := br.Peek(in.maxLen)
+		if len(buf) == 0 {
+			if err != nil && err != io.EOF {
+				return m.total, err
+			}
+			break
 		}
 
-		bi, err := strconv.Atoi(b)
-		if err != nil {
+		inst, args, n := in.match(buf)
+		if inst == nil {
+			br.Discard(1)
+			offset++
 			continue
 		}
 
-		total += ai * bi
+		if !inst.gated || m.enabled {
+			wasEnabled := m.enabled
+			inst.exec(&m, args)
+
+			if obs.exec != nil {
+				obs.exec(execution{offset, inst.name, args})
+			}
+
+			if wasEnabled != m.enabled {
+				if obs.span != nil && spanStart < offset {
+					obs.span(span{spanStart, offset, wasEnabled})
+				}
+				spanStart = offset
+			}
+		}
+
+		br.Discard(n)
+		offset += int64(n)
+	}
+
+	if obs.span != nil && spanStart < offset {
+		obs.span(span{spanStart, offset, m.enabled})
 	}
 
-	return
+	return m.total, nil
 }
 
-func part2(input string) (total int) {
-	enabled := true
-	for _, match := range reCmd.FindAllStringSubmatch(input, -1) {
-		switch {
-		case match[1] == "do":
-			enabled = true
-		case match[2] == "don't":
-			enabled = false
-		case match[3] == "mul":
-			if !enabled {
-				continue
-			}
+// Try to match an instruction at the start of `buf`, returning the
+// instruction, its arguments, and its length in bytes, or a `nil` instruction
+// if there is no match.
+func (in *interpreter) match(buf []byte) (*instruction, []int, int) {
+	for i := range in.instructions {
+		inst := &in.instructions[i]
+		if args, n, ok := inst.parse(buf); ok {
+			return inst, args, n
+		}
+	}
 
-			a, b := match[4], match[5]
-			ai, err := strconv.Atoi(a)
-			if err != nil {
-				continue
-			}
+	return nil, nil, 0
+}
+
+func (inst *instruction) parse(buf []byte) (args []int, n int, ok bool) {
+	if !bytes.HasPrefix(buf, []byte(inst.name)) {
+		return nil, 0, false
+	}
+
+	n = len(inst.name)
+	if n >= len(buf) || buf[n] != '(' {
+		return nil, 0, false
+	}
+	n++
 
-			bi, err := strconv.Atoi(b)
-			if err != nil {
-				continue
+	for a := 0; a < inst.arity; a++ {
+		if a > 0 {
+			if n >= len(buf) || buf[n] != ',' {
+				return nil, 0, false
 			}
+			n++
+		}
+
+		arg, digits := 0, 0
+		for ; n < len(buf) && digits < MAX_DIGITS && '0' <= buf[n] && buf[n] <= '9'; n, digits = n+1, digits+1 {
+			arg = arg*10 + int(buf[n]-'0')
+		}
 
-			total += ai * bi
+		if digits == 0 {
+			return nil, 0, false
 		}
+
+		args = append(args, arg)
+	}
+
+	if n >= len(buf) || buf[n] != ')' {
+		return nil, 0, false
 	}
 
-	return
+	return args, n + 1, true
+}
+
+func (e execution) Format(f fmt.State, _ rune) {
+	fmt.Fprintf(f, "%s(", e.name)
+	for i, arg := range e.args {
+		if i > 0 {
+			fmt.Fprint(f, ",")
+		}
+		fmt.Fprint(f, arg)
+	}
+	fmt.Fprint(f, ")")
 }
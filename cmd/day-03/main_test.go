@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+const (
+	example1 = "xmul(2,4)%&mul[3,7]!@^do_not_mul(5,5)+mul(32,64]then(mul(11,8)mul(8,5))"
+	example2 = "xmul(2,4)&mul[3,7]!^don't()_mul(5,5)+mul(32,64](mul(11,8)undo()?mul(8,5))"
+)
+
+func TestExample(t *testing.T) {
+	if total, err := part1(strings.NewReader(example1)); err != nil || total != 161 {
+		t.Errorf("part 1: expected 161, got %d (err: %v)", total, err)
+	}
+
+	if total, err := part2(strings.NewReader(example2), observer{}); err != nil || total != 48 {
+		t.Errorf("part 2: expected 48, got %d (err: %v)", total, err)
+	}
+}
+
+func TestTrace(t *testing.T) {
+	var execs []string
+	var spans []span
+
+	obs := observer{
+		exec: func(e execution) { execs = append(execs, fmt.Sprintf("%d:%v", e.offset, e)) },
+		span: func(s span) { spans = append(spans, s) },
+	}
+
+	// Read a byte at a time, to check that instructions are recognised across
+	// reads.
+	if _, err := part2(iotest.OneByteReader(strings.NewReader(example2)), obs); err != nil {
+		t.Fatal(err)
+	}
+
+	expectExecs := []string{"1:mul(2,4)", "20:don't()", "59:do()", "64:mul(8,5)"}
+	if !slices.Equal(expectExecs, execs) {
+		t.Errorf("expected executions %v, got %v", expectExecs, execs)
+	}
+
+	expectSpans := []span{{0, 20, true}, {20, 59, false}, {59, int64(len(example2)), true}}
+	if !slices.Equal(expectSpans, spans) {
+		t.Errorf("expected spans %v, got %v", expectSpans, spans)
+	}
+}
+
+func TestCustomInstruction(t *testing.T) {
+	in := newInterpreter()
+	in.register("mul", 2, true, mul)
+	in.register("add3", 3, true, func(m *machine, args []int) {
+		m.total += args[0] + args[1] + args[2]
+	})
+	in.register("neg", 0, false, func(m *machine, _ []int) {
+		m.total = -m.total
+	})
+
+	total, err := in.run(strings.NewReader("mul(2,3)add3(1,2,3)add3(1,2)neg()mul(1000,1)add(4,4)"), observer{})
+	if err != nil || total != -12 {
+		t.Errorf("expected -12, got %d (err: %v)", total, err)
+	}
+}
+
+func TestOverlappingMatch(t *testing.T) {
+	// A failed match must not swallow the start of a real instruction.
+	total, err := part1(strings.NewReader("mumul(2,3)mul(mul(4,5)mul(1,2,3)"))
+	if err != nil || total != 26 {
+		t.Errorf("expected 26, got %d (err: %v)", total, err)
+	}
+}
@@ -2,7 +2,10 @@ package main
 
 import (
 	"bufio"
+	"cmp"
+	"flag"
 	"fmt"
+	"internal/graph"
 	"io"
 	"os"
 	"slices"
@@ -17,8 +20,39 @@ type edge struct {
 	before, after int
 }
 
+var (
+	export  = flag.String("export", "", "write the ordering rules as a graph in this format (dot, adj or csv) instead of solving, highlighting a cycle if there is one")
+	explain = flag.Bool("explain", false, "explain, for each update, which rules it violates and how it should be ordered")
+)
+
 func main() {
+	flag.Parse()
+
 	rules, updates := readInput(os.Stdin)
+	if *export != "" {
+		f, err := graph.ParseFormat(*export)
+		if err != nil {
+			panic(err)
+		}
+
+		g := rules.graph()
+		var highlight graph.Subgraph[int]
+		if cycle, ok := g.FindCycle(); ok {
+			highlight = graph.Cycle(cycle)
+		}
+
+		if err := g.Export(os.Stdout, f, highlight); err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	if *explain {
+		g := rules.graph()
+		for _, update := range updates {
+			explainUpdate(os.Stdout, rules, g, update)
+		}
+	}
 
 	fmt.Println("Part 1:", part1(rules, updates))
 	fmt.Println("Part 2:", part2(rules, updates))
@@ -60,23 +94,55 @@ func (o order) before(a, b int) bool {
 	return ok
 }
 
-// Convert the ordering table into a comparator function
-func (o order) cmp() func(a, b int) int {
-	return func(i, j int) int {
-		switch {
-		case o.before(i, j):
-			return -1
-		case o.before(j, i):
-			return +1
-		default:
-			return 0
+// Convert the ordering table into a directed graph, with an edge from each
+// page to every page that must come after it.
+func (o order) graph() *graph.Graph[int] {
+	edges := make([]edge, 0, len(o))
+	for e := range o {
+		edges = append(edges, e)
+	}
+
+	slices.SortFunc(edges, func(a, b edge) int {
+		return cmp.Or(cmp.Compare(a.before, b.before), cmp.Compare(a.after, b.after))
+	})
+
+	g := graph.NewDirected[int]()
+	for _, e := range edges {
+		g.AddEdge(e.before, e.after)
+	}
+
+	return g
+}
+
+// The rules that `update` breaks, by placing the page that should come after
+// before the page that should come before, in the order they appear in
+// `update`.
+func (o order) violations(update []int) (broken []edge) {
+	for i, after := range update {
+		for _, before := range update[i+1:] {
+			if o.before(before, after) {
+				broken = append(broken, edge{before, after})
+			}
 		}
 	}
+
+	return
+}
+
+// Order the pages in `update` according to the rules that apply to them, in
+// `g` (the rules' graph). Whenever more than one page could go next, the one
+// that comes first in `update` is picked. This does not preserve the relative
+// order of pages that the rules do not order: With the rule 2|3, update 3,1,2
+// is sorted to 1,2,3, because 3 is not ready until 2 is placed. Also returns
+// whether the rules only allow this one order, and fails with a
+// `*graph.CycleError` if the rules contradict each other.
+func sortUpdate(g *graph.Graph[int], update []int) ([]int, bool, error) {
+	return g.Restrict(update).TopologicalSort()
 }
 
 func part1(rules order, updates [][]int) (total int) {
 	for _, update := range updates {
-		if !slices.IsSortedFunc(update, rules.cmp()) {
+		if len(rules.violations(update)) > 0 {
 			continue
 		}
 
@@ -88,20 +154,60 @@ func part1(rules order, updates [][]int) (total int) {
 }
 
 func part2(rules order, updates [][]int) (total int) {
+	g := rules.graph()
 	for _, update := range updates {
-		if slices.IsSortedFunc(update, rules.cmp()) {
+		if len(rules.violations(update)) == 0 {
 			continue
 		}
 
 		// If the criteria is not met, figure out what it would look like if it was
-		// met (in a copy), and then get that copy's middle element.
-		copied := make([]int, len(update))
-		copy(copied, update)
+		// met, and then get that order's middle element.
+		sorted, _, err := sortUpdate(g, update)
+		if err != nil {
+			panic(err)
+		}
 
-		slices.SortStableFunc(copied, rules.cmp())
-		total += copied[len(copied)/2]
+		total += sorted[len(sorted)/2]
 	}
 
 	return
+}
+
+// Describe whether `update` is correctly ordered, and if not, which rules it
+// breaks and what the correct order is. Also flags updates whose pages the
+// rules do not order completely, or order inconsistently.
+func explainUpdate(w io.Writer, rules order, g *graph.Graph[int], update []int) {
+	fmt.Fprint(w, joinPages(update), ": ")
+
+	sorted, unique, err := sortUpdate(g, update)
+	if err != nil {
+		fmt.Fprintln(w, "rules contradict each other,", err)
+		return
+	}
+
+	if broken := rules.violations(update); len(broken) == 0 {
+		fmt.Fprint(w, "correct")
+	} else {
+		var rs []string
+		for _, e := range broken {
+			rs = append(rs, fmt.Sprintf("%d|%d", e.before, e.after))
+		}
+
+		fmt.Fprintf(w, "violates %s, should be %s", strings.Join(rs, " "), joinPages(sorted))
+	}
+
+	if !unique {
+		fmt.Fprint(w, " (ambiguous: rules allow more than one order)")
+	}
+
+	fmt.Fprintln(w)
+}
+
+func joinPages(pages []int) string {
+	tokens := make([]string, len(pages))
+	for i, p := range pages {
+		tokens[i] = strconv.Itoa(p)
+	}
 
+	return strings.Join(tokens, ",")
 }
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"internal/graph"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const example = `47|53
+97|13
+97|61
+97|47
+75|29
+61|13
+75|53
+29|13
+97|29
+53|29
+61|53
+97|53
+61|29
+47|13
+75|47
+97|75
+47|61
+75|61
+47|29
+75|13
+53|13
+
+75,47,61,53,29
+97,61,53,29,13
+75,29,13
+75,97,47,61,53
+61,13,29
+97,13,75,29,47
+`
+
+func TestExample(t *testing.T) {
+	rules, updates := readInput(strings.NewReader(example))
+
+	if actual := part1(rules, updates); actual != 143 {
+		t.Errorf("part 1: expected 143, got %d", actual)
+	}
+
+	if actual := part2(rules, updates); actual != 123 {
+		t.Errorf("part 2: expected 123, got %d", actual)
+	}
+}
+
+func TestViolations(t *testing.T) {
+	rules, _ := readInput(strings.NewReader(example))
+
+	expect := []edge{{75, 13}, {29, 13}, {47, 13}, {47, 29}}
+	if actual := rules.violations([]int{97, 13, 75, 29, 47}); !slices.Equal(expect, actual) {
+		t.Errorf("expected %v, got %v", expect, actual)
+	}
+}
+
+func TestSortUpdate(t *testing.T) {
+	rules, _ := readInput(strings.NewReader(example))
+	g := rules.graph()
+
+	sorted, unique, err := sortUpdate(g, []int{97, 13, 75, 29, 47})
+	if err != nil || !unique || !slices.Equal(sorted, []int{97, 75, 47, 29, 13}) {
+		t.Errorf("expected unique order 97,75,47,29,13, got %v (unique: %v, err: %v)", sorted, unique, err)
+	}
+
+	// There are no rules about page 99, so it could go anywhere, and keeps its
+	// place at the front.
+	sorted, unique, err = sortUpdate(g, []int{99, 75, 47})
+	if err != nil || unique || !slices.Equal(sorted, []int{99, 75, 47}) {
+		t.Errorf("expected ambiguous order 99,75,47, got %v (unique: %v, err: %v)", sorted, unique, err)
+	}
+	// Ties are broken by position in the update, among the pages that are
+	// ready, which can move unconstrained pages past constrained ones.
+	rules, _ = readInput(strings.NewReader("2|3\n\n3,1,2\n"))
+	sorted, _, err = sortUpdate(rules.graph(), []int{3, 1, 2})
+	if err != nil || !slices.Equal(sorted, []int{1, 2, 3}) {
+		t.Errorf("expected order 1,2,3, got %v (err: %v)", sorted, err)
+	}
+}
+
+func TestSortUpdateCycle(t *testing.T) {
+	rules, _ := readInput(strings.NewReader("1|2\n2|3\n3|1\n\n1,2,3\n"))
+
+	_, _, err := sortUpdate(rules.graph(), []int{3, 2, 1})
+
+	var cycle *graph.CycleError[int]
+	if !errors.As(err, &cycle) || len(cycle.Cycle) != 3 {
+		t.Errorf("expected a cycle of 3 pages, got %v", err)
+	}
+}
+
+func TestExplainUpdate(t *testing.T) {
+	rules, _ := readInput(strings.NewReader(example))
+
+	var out strings.Builder
+	explainUpdate(&out, rules, rules.graph(), []int{61, 13, 29})
+
+	if expect := "61,13,29: violates 29|13, should be 61,29,13\n"; out.String() != expect {
+		t.Errorf("expected %q, got %q", expect, out.String())
+	}
+}
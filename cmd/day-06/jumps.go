@@ -0,0 +1,86 @@
+package main
+
+import (
+	"internal/grid"
+	"math/bits"
+)
+
+// For every cell and direction, how many steps the guard can take from that
+// cell in that direction before it reaches a block, or the edge of the grid.
+type jumps struct {
+	width, height int
+	steps         []int32
+}
+
+var DIRS = []grid.Dir{grid.DIR_U, grid.DIR_R, grid.DIR_D, grid.DIR_L}
+
+func newJumps(g *grid.Grid[cell]) *jumps {
+	j := &jumps{g.Width, g.Height, make([]int32, g.Width*g.Height*4)}
+
+	// Each cell's jump is one more than the jump from the cell ahead of it, so
+	// visit cells in an order that reaches the cell ahead first.
+	cells := g.Width * g.Height
+	for _, d := range DIRS {
+		for i := range cells {
+			if d == grid.DIR_D || d == grid.DIR_R {
+				i = cells - 1 - i
+			}
+
+			x, y := i%g.Width, i/g.Width
+			aheadX, aheadY := d.Move(x, y, 1)
+			if ahead := g.Get(aheadX, aheadY); ahead != nil && *ahead != BLOCK {
+				j.steps[j.index(x, y, d)] = j.steps[j.index(aheadX, aheadY, d)] + 1
+			}
+		}
+	}
+
+	return j
+}
+
+func (j *jumps) index(x, y int, d grid.Dir) int {
+	return (y*j.width+x)<<2 | bits.TrailingZeros(uint(d))
+}
+
+func (j *jumps) inBounds(x, y int) bool {
+	return 0 <= x && x < j.width && 0 <= y && y < j.height
+}
+
+// Whether the guard, starting at (`x`, `y`) facing up, ends up walking in a
+// loop once a block is added at (`blockX`, `blockY`).
+//
+// The table does not know about the added block, so rather than patching it,
+// each jump is cut short locally if the added block is in its way. `seen` is
+// used to detect loops: It is marked with `trial` for each configuration the
+// guard turns in, and must not already contain `trial` anywhere.
+func (j *jumps) loops(x, y, blockX, blockY int, seen []int32, trial int32) bool {
+	dir := grid.DIR_U
+	for {
+		i := j.index(x, y, dir)
+		if seen[i] == trial {
+			return true
+		}
+		seen[i] = trial
+
+		steps := int(j.steps[i])
+		if k := distance(x, y, blockX, blockY, dir); 0 < k && k <= steps+1 {
+			steps = k - 1
+		} else if aheadX, aheadY := dir.Move(x, y, steps+1); !j.inBounds(aheadX, aheadY) {
+			return false
+		}
+
+		x, y = dir.Move(x, y, steps)
+		dir = dir.RotateClockwise()
+	}
+}
+
+// How many steps it takes to get from (`x`, `y`) to (`toX`, `toY`) moving in
+// direction `d`, or a non-positive number if it can't be reached that way.
+func distance(x, y, toX, toY int, d grid.Dir) int {
+	dx, dy := d.Move(0, 0, 1)
+	k := (toX-x)*dx + (toY-y)*dy
+	if movedX, movedY := d.Move(x, y, k); movedX != toX || movedY != toY {
+		return 0
+	}
+
+	return k
+}
@@ -3,7 +3,9 @@ package main
 import (
 	"fmt"
 	"internal/grid"
+	"internal/point"
 	"os"
+	"runtime"
 	"sync"
 	"sync/atomic"
 )
@@ -23,6 +25,9 @@ const (
 	VISIT_L
 )
 
+// Any of the visit states.
+const VISIT = VISIT_U | VISIT_R | VISIT_D | VISIT_L
+
 func main() {
 	grid := grid.ReadFunc(os.Stdin, func(b byte) cell {
 		switch b {
@@ -49,31 +54,59 @@ func part1(g *grid.Grid[cell]) int {
 	return total
 }
 
+// Count the positions where adding a single block would trap the guard in a
+// loop. Only positions on the guard's original path can change its route, so
+// those are the only ones tried (apart from its starting position). Each trial
+// jumps the guard straight from one turn to the next using a table of how far
+// it can walk in each direction, which is shared between a fixed pool of
+// workers.
 func part2(g *grid.Grid[cell]) int {
-	var found atomic.Uint64
+	startX, startY, found := g.Find(GUARD)
+	if !found {
+		panic("Guard not found")
+	}
+
+	j := newJumps(g)
+	candidates := path(g)
+	workers := runtime.GOMAXPROCS(0)
 
+	var trapped atomic.Uint64
 	var wg sync.WaitGroup
-	for y := 0; y < g.Height; y++ {
-		for x := 0; x < g.Width; x++ {
-			if *g.Get(x, y) != EMPTY {
-				continue
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// `seen` records the last trial (offset by one) that visited each
+			// configuration, so that it does not need to be cleared between
+			// trials.
+			seen := make([]int32, len(j.steps))
+			for c := w; c < len(candidates); c += workers {
+				block := candidates[c]
+				if j.loops(startX, startY, block.X, block.Y, seen, int32(c+1)) {
+					trapped.Add(1)
+				}
 			}
+		}()
+	}
+
+	wg.Wait()
+	return int(trapped.Load())
+}
 
-			wg.Add(1)
-			clone := g.Copy()
-			*clone.Get(x, y) = BLOCK
+// The positions the guard visits on its way out of the grid, apart from where
+// it starts.
+func path(g *grid.Grid[cell]) (cells []point.Point) {
+	visited := g.Copy()
+	traverse(visited)
 
-			go func() {
-				defer wg.Done()
-				if traverse(clone) {
-					found.Add(1)
-				}
-			}()
+	for x, y := range visited.Coords() {
+		if c := *visited.Get(x, y); c&VISIT != 0 && c&GUARD == 0 {
+			cells = append(cells, point.New(x, y))
 		}
 	}
 
-	wg.Wait()
-	return int(found.Load())
+	return
 }
 
 // Simulate the guard walking across the grid, filling in cells as they go.
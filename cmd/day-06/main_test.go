@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"internal/grid"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+const example = `....#.....
+.........#
+..........
+..#.......
+.......#..
+..........
+.#..^.....
+........#.
+#.........
+......#...
+`
+
+func readExample(s string) *grid.Grid[cell] {
+	return grid.ReadFunc(strings.NewReader(s), func(b byte) cell {
+		switch b {
+		case '#':
+			return BLOCK
+		case '^':
+			return GUARD
+		default:
+			return EMPTY
+		}
+	})
+}
+
+// A `width` by `height` map, where each cell is blocked with probability
+// `density`, and the guard starts somewhere that isn't blocked. As in the
+// puzzle, the guard always leaves the map, unless a block is added.
+func randomMap(r *rand.Rand, width, height int, density float64) *grid.Grid[cell] {
+	for {
+		g := grid.New[cell](width, height)
+		for x, y := range g.Coords() {
+			if r.Float64() < density {
+				*g.Get(x, y) = BLOCK
+			}
+		}
+
+		c := g.Get(r.Intn(width), r.Intn(height))
+		if *c != EMPTY {
+			continue
+		}
+
+		*c = GUARD
+		if !traverse(g.Copy()) {
+			return g
+		}
+	}
+}
+
+// A `size` by `size` map where the guard spirals out from the middle, with
+// `gap` more steps on each lap, before leaving the map, which makes for a much
+// longer path than a random map. Cells off the path are blocked with
+// probability `density`, which doesn't affect the guard's path.
+func spiralMap(r *rand.Rand, size, gap int, density float64) *grid.Grid[cell] {
+	g := grid.New[cell](size, size)
+	x, y, dir := size/2, size/2, grid.DIR_U
+	*g.Get(x, y) = GUARD
+
+	for i := 0; ; i++ {
+		x, y = dir.Move(x, y, gap*(i/2+1))
+		blockX, blockY := dir.Move(x, y, 1)
+		block := g.Get(blockX, blockY)
+		if block == nil {
+			break
+		}
+
+		*block = BLOCK
+
+		dir = dir.RotateClockwise()
+	}
+
+	visited := g.Copy()
+	traverse(visited)
+	for x, y := range g.Coords() {
+		if *visited.Get(x, y) == EMPTY && r.Float64() < density {
+			*g.Get(x, y) = BLOCK
+		}
+	}
+
+	return g
+}
+
+func TestExample(t *testing.T) {
+	g := readExample(example)
+	if actual := part1(g.Copy()); actual != 41 {
+		t.Errorf("part 1: expected 41, got %d", actual)
+	}
+
+	if actual := part2(g.Copy()); actual != 6 {
+		t.Errorf("part 2: expected 6, got %d", actual)
+	}
+}
+
+func TestPart2LeavesGrid(t *testing.T) {
+	g := readExample(example)
+	before := fmt.Sprint(g)
+	part2(g)
+
+	if after := fmt.Sprint(g); before != after {
+		t.Errorf("expected grid to be left untouched, got:\n%s", after)
+	}
+}
+
+func TestJumps(t *testing.T) {
+	g := readExample(example)
+	j := newJumps(g)
+
+	for _, tc := range []struct {
+		x, y  int
+		dir   grid.Dir
+		steps int32
+	}{
+		{4, 6, grid.DIR_U, 5},
+		{4, 1, grid.DIR_R, 4},
+		{8, 1, grid.DIR_D, 5},
+		{8, 6, grid.DIR_L, 6},
+		{0, 0, grid.DIR_U, 0},
+		{0, 0, grid.DIR_L, 0},
+		{9, 2, grid.DIR_D, 7},
+	} {
+		if actual := j.steps[j.index(tc.x, tc.y, tc.dir)]; actual != tc.steps {
+			t.Errorf("(%d, %d) facing %d: expected %d steps, got %d", tc.x, tc.y, tc.dir, tc.steps, actual)
+		}
+	}
+}
+
+func TestPart2MatchesNaive(t *testing.T) {
+	r := rand.New(rand.NewSource(6))
+	for i := 0; i < 300; i++ {
+		g := randomMap(r, 1+r.Intn(30), 1+r.Intn(30), r.Float64()*0.3)
+		if expect, actual := part2Naive(g.Copy()), part2(g.Copy()); expect != actual {
+			t.Fatalf("expected %d, got %d for:\n%v", expect, actual, g)
+		}
+	}
+}
+
+func TestSpiralMap(t *testing.T) {
+	g := spiralMap(rand.New(rand.NewSource(6)), 50, 3, 0.1)
+	if traverse(g.Copy()) {
+		t.Fatalf("expected the guard to leave:\n%v", g)
+	}
+
+	if expect, actual := part2Naive(g.Copy()), part2(g.Copy()); expect != actual {
+		t.Fatalf("expected %d, got %d for:\n%v", expect, actual, g)
+	}
+}
+
+func BenchmarkPart2(b *testing.B) {
+	// Random maps give the guard short paths before it leaves, so most of the
+	// work is in setting up.
+	for _, density := range []float64{0.005, 0.01, 0.02} {
+		g := randomMap(rand.New(rand.NewSource(1000)), 1000, 1000, density)
+		b.Run(fmt.Sprintf("random/%v", density), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				part2(g)
+			}
+		})
+	}
+
+	// Spirals give the guard paths that cover a large part of the map, and
+	// many turns to make on each of them.
+	for _, gap := range []int{4, 16, 64} {
+		g := spiralMap(rand.New(rand.NewSource(1000)), 1000, gap, 0.01)
+		b.Run(fmt.Sprintf("spiral/%d", gap), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				part2(g)
+			}
+		})
+	}
+}
+
+// The original implementation of `part2`, kept as a reference: Try adding a
+// block at every empty cell, by walking the guard cell by cell across a fresh
+// copy of the grid, concurrently.
+func part2Naive(g *grid.Grid[cell]) int {
+	var found atomic.Uint64
+
+	var wg sync.WaitGroup
+	for y := 0; y < g.Height; y++ {
+		for x := 0; x < g.Width; x++ {
+			if *g.Get(x, y) != EMPTY {
+				continue
+			}
+
+			wg.Add(1)
+			clone := g.Copy()
+			*clone.Get(x, y) = BLOCK
+
+			go func() {
+				defer wg.Done()
+				if traverse(clone) {
+					found.Add(1)
+				}
+			}()
+		}
+	}
+
+	wg.Wait()
+	return int(found.Load())
+}
@@ -2,8 +2,10 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
 	"io"
+	"math/big"
 	"os"
 	"strconv"
 	"strings"
@@ -11,19 +13,62 @@ import (
 
 type equation struct {
 	target int64
-	terms  []term
+	terms  []int64
 }
 
-type term struct {
-	value int64
-	pad10 int64
+// Operators filling the gaps between an equation's terms, so that it hits its
+// target.
+type solution struct {
+	eq  equation
+	ops []*operator
 }
 
+// Searches for solutions to an equation, using a particular set of operators.
+type solver struct {
+	eq  equation
+	ops []operator
+
+	// The operators chosen so far, `chosen[i]` sitting between `terms[i]` and
+	// `terms[i + 1]`.
+	chosen []*operator
+
+	// Whether the search skipped any possibilities because a value did not fit
+	// in an `int64`.
+	overflow bool
+}
+
+var (
+	show   = flag.String("show", "", "print the 'first' or 'all' solutions to each equation, using the operators in -ops")
+	opList = flag.String("ops", "+,*,||", "comma-separated operators to use with -show, from +, -, *, /, || and ^")
+)
+
 func main() {
+	flag.Parse()
+
 	equations := readInput(os.Stdin)
 
 	fmt.Println("Part 1:", part1(equations))
 	fmt.Println("Part 2:", part2(equations))
+
+	if *show != "" {
+		var ops []operator
+		for _, symbol := range strings.Split(*opList, ",") {
+			op, ok := OPERATORS[symbol]
+			if !ok {
+				panic(fmt.Sprintf("unknown operator %q", symbol))
+			}
+			ops = append(ops, op)
+		}
+
+		for _, e := range equations {
+			for s := range solutions(e, ops) {
+				fmt.Println(s)
+				if *show == "first" {
+					break
+				}
+			}
+		}
+	}
 }
 
 func readInput(r io.Reader) (equations []equation) {
@@ -40,8 +85,7 @@ func readInput(r io.Reader) (equations []equation) {
 		eq.target, _ = strconv.ParseInt(target, 10, 64)
 		for _, field := range strings.Fields(rest) {
 			t, _ := strconv.ParseInt(field, 10, 64)
-			pad10 := Pow(10, int64(len(field)))
-			eq.terms = append(eq.terms, term{t, pad10})
+			eq.terms = append(eq.terms, t)
 		}
 
 		equations = append(equations, eq)
@@ -54,7 +98,7 @@ func part1(equations []equation) int64 {
 	total := int64(0)
 
 	for _, e := range equations {
-		if isSatisfiable(e.target, e.terms /* withConcat */, false) {
+		if isSatisfiable(e, []operator{ADD, MUL}) {
 			total += e.target
 		}
 	}
@@ -64,7 +108,7 @@ func part1(equations []equation) int64 {
 
 func part2(equations []equation) (total int64) {
 	for _, e := range equations {
-		if isSatisfiable(e.target, e.terms /* withConcat */, true) {
+		if isSatisfiable(e, []operator{ADD, MUL, CONCAT}) {
 			total += e.target
 		}
 	}
@@ -72,43 +116,170 @@ func part2(equations []equation) (total int64) {
 	return
 }
 
-// Returns true if ther is some combination of operations that will result in
+// Returns true if there is some combination of operations that will result in
 // `e` satisfying its target.
-func isSatisfiable(target int64, terms []term, withConcat bool) bool {
-	if len(terms) == 1 {
-		return terms[0].value == target
+func isSatisfiable(e equation, ops []operator) bool {
+	for range solutions(e, ops) {
+		return true
 	}
 
-	last := terms[len(terms)-1]
-	rest := terms[:len(terms)-1]
+	return false
+}
 
-	if target%last.value == 0 && isSatisfiable(target/last.value, rest, withConcat) {
-		return true
+// Returns every way of placing operators from `ops` between the terms of `e`
+// to hit its target.
+//
+// The search works backwards from the target: the last operator must turn the
+// value of all the other terms into the target, so inverting it gives the
+// target for the remaining terms. If an intermediate value overflows an
+// `int64`, the affected possibilities are revisited by evaluating every
+// combination of operators with `math/big`.
+func solutions(e equation, ops []operator) func(yield func(solution) bool) {
+	return func(yield func(solution) bool) {
+		if len(e.terms) == 0 {
+			return
+		}
+
+		s := &solver{e, ops, make([]*operator, len(e.terms)-1), false}
+		if !s.backward(e.target, len(e.terms)-1, yield) || !s.overflow {
+			return
+		}
+
+		// Some possibilities were skipped, so start again with arbitrary
+		// precision, skipping the solutions that have already been found.
+		target := big.NewInt(e.target)
+		s.forwardBig(big.NewInt(e.terms[0]), 1, func() bool {
+			if _, st := s.eval(); st == OK {
+				return true
+			}
+
+			return yield(s.solution())
+		}, target)
 	}
+}
 
-	if target > last.value && isSatisfiable(target-last.value, rest, withConcat) {
+// Find operators for the first `k + 1` terms to evaluate to `target`,
+// assuming the operators after that have already been chosen.
+func (s *solver) backward(target int64, k int, yield func(solution) bool) bool {
+	if k == 0 {
+		if s.eq.terms[0] == target {
+			return yield(s.solution())
+		}
 		return true
 	}
 
-	if withConcat && (target-last.value)%last.pad10 == 0 && isSatisfiable(target/last.pad10, rest, withConcat) {
+	b := s.eq.terms[k]
+	for i := range s.ops {
+		op := &s.ops[i]
+		s.chosen[k-1] = op
+
+		as, st := op.invert(target, b)
+		switch st {
+		case OVERFLOW:
+			s.overflow = true
+
+		case UNBOUNDED:
+			// Too many candidates to invert, so evaluate the remaining terms
+			// forwards instead.
+			ok := s.forward(s.eq.terms[0], 1, k, func(a int64) bool {
+				if r, st := op.apply(a, b); st == OK && r == target {
+					return yield(s.solution())
+				}
+				return true
+			})
+
+			if !ok {
+				return false
+			}
+
+		case OK:
+			for _, a := range as {
+				if !s.backward(a, k-1, yield) {
+					return false
+				}
+			}
+		}
+	}
+
+	return true
+}
+
+// Evaluate every combination of operators for the first `k` terms, starting
+// with `acc` as the value of the first `i` terms.
+func (s *solver) forward(acc int64, i, k int, fn func(int64) bool) bool {
+	if i == k {
+		return fn(acc)
+	}
+
+	for j := range s.ops {
+		op := &s.ops[j]
+		r, st := op.apply(acc, s.eq.terms[i])
+		if st == OVERFLOW {
+			s.overflow = true
+		}
+
+		if st != OK {
+			continue
+		}
+
+		s.chosen[i-1] = op
+		if !s.forward(r, i+1, k, fn) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Like `forward`, but over all terms, with arbitrary precision, calling `fn`
+// for every combination of operators that hits `target`.
+func (s *solver) forwardBig(acc *big.Int, i int, fn func() bool, target *big.Int) bool {
+	if i == len(s.eq.terms) {
+		if acc.Cmp(target) == 0 {
+			return fn()
+		}
 		return true
 	}
 
-	return false
+	b := big.NewInt(s.eq.terms[i])
+	for j := range s.ops {
+		op := &s.ops[j]
+		r := op.applyBig(acc, b)
+		if r == nil {
+			continue
+		}
+
+		s.chosen[i-1] = op
+		if !s.forwardBig(r, i+1, fn, target) {
+			return false
+		}
+	}
+
+	return true
 }
 
-// Logarithmic time integer exponentiation
-func Pow(x, y int64) int64 {
-	result, base, exp := int64(1), x, y
-	for exp > 0 {
-		if exp&1 == 0 {
-			base *= base
-			exp >>= 1
-		} else {
-			result *= base
-			exp--
+// Evaluate the equation with the chosen operators, in `int64`.
+func (s *solver) eval() (int64, status) {
+	acc := s.eq.terms[0]
+	for i, op := range s.chosen {
+		var st status
+		if acc, st = op.apply(acc, s.eq.terms[i+1]); st != OK {
+			return 0, st
 		}
 	}
 
-	return result
+	return acc, OK
+}
+
+func (s *solver) solution() solution {
+	ops := make([]*operator, len(s.chosen))
+	copy(ops, s.chosen)
+	return solution{s.eq, ops}
+}
+
+func (s solution) Format(f fmt.State, _ rune) {
+	fmt.Fprintf(f, "%d = %d", s.eq.target, s.eq.terms[0])
+	for i, op := range s.ops {
+		fmt.Fprintf(f, " %s %d", op.symbol, s.eq.terms[i+1])
+	}
 }
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const example = `190: 10 19
+3267: 81 40 27
+83: 17 5
+156: 15 6
+7290: 6 8 6 15
+161011: 16 10 13
+192: 17 8 14
+21037: 9 7 18 13
+292: 11 6 16 20
+`
+
+func allSolutions(e equation, ops []operator) (found []string) {
+	for s := range solutions(e, ops) {
+		found = append(found, fmt.Sprint(s))
+	}
+
+	slices.Sort(found)
+	return
+}
+
+func TestExample(t *testing.T) {
+	equations := readInput(strings.NewReader(example))
+
+	if actual := part1(equations); actual != 3749 {
+		t.Errorf("part 1: expected 3749, got %d", actual)
+	}
+
+	if actual := part2(equations); actual != 11387 {
+		t.Errorf("part 2: expected 11387, got %d", actual)
+	}
+}
+
+func TestSolutions(t *testing.T) {
+	e := equation{3267, []int64{81, 40, 27}}
+	expect := []string{"3267 = 81 * 40 + 27", "3267 = 81 + 40 * 27"}
+	if actual := allSolutions(e, []operator{ADD, MUL}); !slices.Equal(expect, actual) {
+		t.Errorf("expected %v, got %v", expect, actual)
+	}
+}
+
+func TestExtraOperators(t *testing.T) {
+	for _, tc := range []struct {
+		e      equation
+		expect []string
+	}{
+		{equation{-5, []int64{3, 8}}, []string{"-5 = 3 - 8"}},
+		{equation{3, []int64{17, 5}}, []string{"3 = 17 / 5"}},
+		{equation{-3, []int64{-17, 5}}, []string{"-3 = -17 / 5"}},
+		{equation{8, []int64{2, 3}}, []string{"8 = 2 ^ 3"}},
+		{equation{1, []int64{7, 0}}, []string{"1 = 7 ^ 0"}},
+		{equation{-27, []int64{-3, 3}}, []string{"-27 = -3 ^ 3"}},
+		{equation{-53, []int64{-5, 3}}, []string{"-53 = -5 || 3"}},
+		{equation{0, []int64{4, 3, 0}}, []string{"0 = 4 * 3 * 0", "0 = 4 + 3 * 0", "0 = 4 - 3 * 0", "0 = 4 / 3 * 0", "0 = 4 ^ 3 * 0", "0 = 4 || 3 * 0"}},
+	} {
+		if actual := allSolutions(tc.e, []operator{ADD, SUB, MUL, DIV, CONCAT, EXP}); !slices.Equal(tc.expect, actual) {
+			t.Errorf("expected %v, got %v", tc.expect, actual)
+		}
+	}
+}
+
+func TestOverflow(t *testing.T) {
+	// Multiplying first takes the intermediate value past the range of an
+	// int64, so this solution can only be found with arbitrary precision.
+	e := equation{1 << 62, []int64{1 << 62, 4, 4}}
+	expect := []string{
+		"4611686018427387904 = 4611686018427387904 * 4 / 4",
+		"4611686018427387904 = 4611686018427387904 / 4 * 4",
+	}
+
+	if actual := allSolutions(e, []operator{MUL, DIV}); !slices.Equal(expect, actual) {
+		t.Errorf("expected %v, got %v", expect, actual)
+	}
+}
+
+func TestLargeRoots(t *testing.T) {
+	for _, tc := range []struct {
+		e      equation
+		expect []string
+	}{
+		// Too large to be rooted precisely through a float64.
+		{equation{4611686018427400000, []int64{4611686018427400000, 1}}, []string{"4611686018427400000 = 4611686018427400000 ^ 1"}},
+		{equation{3037000499 * 3037000499, []int64{3037000499, 2}}, []string{"9223372030926249001 = 3037000499 ^ 2"}},
+		{equation{999999999999999999, []int64{999999, 3}}, nil},
+		{equation{math.MinInt64, []int64{-2, 63}}, []string{"-9223372036854775808 = -2 ^ 63"}},
+	} {
+		if actual := allSolutions(tc.e, []operator{EXP}); !slices.Equal(tc.expect, actual) {
+			t.Errorf("expected %v, got %v", tc.expect, actual)
+		}
+	}
+
+	for _, tc := range []struct {
+		t, b   int64
+		expect []int64
+	}{
+		{1 << 62, 2, []int64{1 << 31, -(1 << 31)}},
+		{999999999999999999, 3, nil},
+		{999999 * 999999 * 999999, 3, []int64{999999}},
+		{math.MaxInt64, 1, []int64{math.MaxInt64}},
+	} {
+		if actual, st := EXP.invert(tc.t, tc.b); st != OK || !slices.Equal(tc.expect, actual) {
+			t.Errorf("%d ^ (1/%d): expected %v, got %v (status %d)", tc.t, tc.b, tc.expect, actual, st)
+		}
+	}
+}
+
+// Evaluate every combination of operators forwards, without using any
+// inverses.
+func naiveSolutions(e equation, ops []operator) (found []string) {
+	s := &solver{e, ops, make([]*operator, len(e.terms)-1), false}
+	s.forward(e.terms[0], 1, len(e.terms), func(v int64) bool {
+		if v == e.target {
+			found = append(found, fmt.Sprint(s.solution()))
+		}
+		return true
+	})
+
+	slices.Sort(found)
+	return
+}
+
+func TestMatchesNaive(t *testing.T) {
+	r := rand.New(rand.NewSource(0))
+	ops := []operator{ADD, SUB, MUL, DIV, CONCAT, EXP}
+
+	for i := 0; i < 500; i++ {
+		terms := make([]int64, 2+r.Intn(3))
+		for j := range terms {
+			terms[j] = int64(r.Intn(12) - 2)
+		}
+
+		// Pick a target that is reachable, by evaluating a random combination.
+		s := &solver{equation{0, terms}, ops, make([]*operator, len(terms)-1), false}
+		for j := range s.chosen {
+			s.chosen[j] = &ops[r.Intn(len(ops))]
+		}
+
+		target, st := s.eval()
+		if st != OK {
+			continue
+		}
+
+		e := equation{target, terms}
+		if expect, actual := naiveSolutions(e, ops), allSolutions(e, ops); !slices.Equal(expect, actual) {
+			t.Fatalf("%v: expected %v, got %v", e, expect, actual)
+		}
+	}
+}
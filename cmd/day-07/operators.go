@@ -0,0 +1,349 @@
+package main
+
+import (
+	"math"
+	"math/big"
+	"slices"
+)
+
+// An operator that can be placed between terms in an equation. Equations are
+// always evaluated left-to-right, so an operator combines the value of all the
+// terms to its left, `a`, with the term to its right, `b`.
+type operator struct {
+	symbol string
+
+	apply func(a, b int64) (int64, status)
+
+	// Find every `a` such that `apply(a, b) == t`. Returns `UNBOUNDED` if there
+	// are too many to list, in which case the solver tries each value of `a`
+	// instead.
+	invert func(t, b int64) ([]int64, status)
+
+	// Like `apply`, but for when values no longer fit in an `int64`. Returns
+	// `nil` if the operation is undefined.
+	applyBig func(a, b *big.Int) *big.Int
+}
+
+// The outcome of applying or inverting an operator.
+type status byte
+
+const (
+	OK status = iota
+
+	// There is no result (e.g. division by zero), or no inverse.
+	UNDEFINED
+
+	// The result does not fit in an `int64`.
+	OVERFLOW
+
+	// The inverse has too many solutions to list.
+	UNBOUNDED
+)
+
+// The largest result that `applyBig` will compute for `^`, in bits, to avoid
+// running out of memory on enormous powers.
+const MAX_BIG_BITS = 1 << 16
+
+var ADD = operator{
+	symbol: "+",
+	apply:  add,
+	invert: func(t, b int64) ([]int64, status) {
+		return single(sub(t, b))
+	},
+	applyBig: func(a, b *big.Int) *big.Int {
+		return new(big.Int).Add(a, b)
+	},
+}
+
+var SUB = operator{
+	symbol: "-",
+	apply:  sub,
+	invert: func(t, b int64) ([]int64, status) {
+		return single(add(t, b))
+	},
+	applyBig: func(a, b *big.Int) *big.Int {
+		return new(big.Int).Sub(a, b)
+	},
+}
+
+var MUL = operator{
+	symbol: "*",
+	apply:  mul,
+	invert: func(t, b int64) ([]int64, status) {
+		switch {
+		case b == 0 && t == 0:
+			return nil, UNBOUNDED
+		case b == 0 || t%b != 0:
+			return nil, UNDEFINED
+		case t == math.MinInt64 && b == -1:
+			return nil, OVERFLOW
+		default:
+			return []int64{t / b}, OK
+		}
+	},
+	applyBig: func(a, b *big.Int) *big.Int {
+		return new(big.Int).Mul(a, b)
+	},
+}
+
+// Integer division, truncating towards zero.
+var DIV = operator{
+	symbol: "/",
+	apply: func(a, b int64) (int64, status) {
+		switch {
+		case b == 0:
+			return 0, UNDEFINED
+		case a == math.MinInt64 && b == -1:
+			return 0, OVERFLOW
+		default:
+			return a / b, OK
+		}
+	},
+	invert: func(t, b int64) (as []int64, st status) {
+		if b == 0 {
+			return nil, UNDEFINED
+		}
+
+		// Every `a` with `a / b == t` is within `|b| - 1` of `t * b`.
+		base, st := mul(t, b)
+		if st != OK {
+			return nil, st
+		}
+
+		width := b
+		if width < 0 {
+			width = -width
+		}
+
+		for r := -(width - 1); r < width; r++ {
+			a, st := add(base, r)
+			if st == OK && a/b == t {
+				as = append(as, a)
+			}
+		}
+
+		return as, OK
+	},
+	applyBig: func(a, b *big.Int) *big.Int {
+		if b.Sign() == 0 {
+			return nil
+		}
+		return new(big.Int).Quo(a, b)
+	},
+}
+
+// Concatenation of decimal digits, only defined for non-negative right-hand
+// sides.
+var CONCAT = operator{
+	symbol: "||",
+	apply: func(a, b int64) (int64, status) {
+		pad, st := pad10(b)
+		if st != OK {
+			return 0, st
+		}
+
+		shifted, st := mul(a, pad)
+		if st != OK {
+			return 0, st
+		}
+
+		if a < 0 {
+			return sub(shifted, b)
+		}
+
+		return add(shifted, b)
+	},
+	invert: func(t, b int64) ([]int64, status) {
+		pad, st := pad10(b)
+		if st != OK {
+			return nil, st
+		}
+
+		// The last digits of `t` must be `b`, in magnitude.
+		switch {
+		case t >= 0 && t%pad == b:
+			return []int64{t / pad}, OK
+		case t < 0 && -(t%pad) == b && t/pad != 0:
+			return []int64{t / pad}, OK
+		default:
+			return nil, UNDEFINED
+		}
+	},
+	applyBig: func(a, b *big.Int) *big.Int {
+		if b.Sign() < 0 {
+			return nil
+		}
+
+		pad := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(len(b.String()))), nil)
+		r := new(big.Int).Mul(a, pad)
+		if a.Sign() < 0 {
+			return r.Sub(r, b)
+		}
+		return r.Add(r, b)
+	},
+}
+
+// Exponentiation, only defined for non-negative exponents.
+var EXP = operator{
+	symbol: "^",
+	apply: func(a, b int64) (int64, status) {
+		if b < 0 {
+			return 0, UNDEFINED
+		}
+		return pow(a, b)
+	},
+	invert: func(t, b int64) ([]int64, status) {
+		switch {
+		case b < 0:
+			return nil, UNDEFINED
+		case b == 0 && t == 1:
+			return nil, UNBOUNDED
+		case b == 0:
+			return nil, UNDEFINED
+		}
+
+		if b == 1 {
+			return []int64{t}, OK
+		}
+
+		// Find the integer `b`-th root of `t`'s magnitude, and then check it and
+		// the integer after it (the magnitude of `math.MinInt64` is not a
+		// positive `int64`). Even powers have a positive and a negative root.
+		if t < 0 && b%2 == 0 {
+			return nil, UNDEFINED
+		}
+
+		r := root(magnitude(t), b)
+
+		var as []int64
+		for _, a := range []int64{r, -r, r + 1, -(r + 1)} {
+			if p, st := pow(a, b); st == OK && p == t && !slices.Contains(as, a) {
+				as = append(as, a)
+			}
+		}
+
+		return as, OK
+	},
+	applyBig: func(a, b *big.Int) *big.Int {
+		if b.Sign() < 0 || !b.IsInt64() {
+			return nil
+		}
+
+		if a.CmpAbs(big.NewInt(1)) > 0 && int64(a.BitLen())*b.Int64() > MAX_BIG_BITS {
+			return nil
+		}
+
+		return new(big.Int).Exp(a, b, nil)
+	},
+}
+
+// Operators by their symbol, for choosing them on the command line.
+var OPERATORS = map[string]operator{
+	ADD.symbol:    ADD,
+	SUB.symbol:    SUB,
+	MUL.symbol:    MUL,
+	DIV.symbol:    DIV,
+	CONCAT.symbol: CONCAT,
+	EXP.symbol:    EXP,
+}
+
+func add(a, b int64) (int64, status) {
+	r := a + b
+	if (a > 0 && b > 0 && r < 0) || (a < 0 && b < 0 && r >= 0) {
+		return 0, OVERFLOW
+	}
+	return r, OK
+}
+
+func sub(a, b int64) (int64, status) {
+	r := a - b
+	if (a >= 0 && b < 0 && r < 0) || (a < 0 && b > 0 && r >= 0) {
+		return 0, OVERFLOW
+	}
+	return r, OK
+}
+
+func mul(a, b int64) (int64, status) {
+	if a == 0 || b == 0 {
+		return 0, OK
+	}
+
+	r := a * b
+	if r/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, OVERFLOW
+	}
+	return r, OK
+}
+
+// Logarithmic time integer exponentiation, with overflow detection.
+func pow(x, y int64) (int64, status) {
+	result, base, exp := int64(1), x, y
+	for exp > 0 {
+		var st status
+		if exp&1 == 1 {
+			if result, st = mul(result, base); st != OK {
+				return 0, st
+			}
+		}
+
+		if exp >>= 1; exp > 0 {
+			if base, st = mul(base, base); st != OK {
+				return 0, st
+			}
+		}
+	}
+
+	return result, OK
+}
+
+// The magnitude of `t`, which always fits in a `uint64`.
+func magnitude(t int64) uint64 {
+	if t < 0 {
+		return uint64(-(t + 1)) + 1
+	}
+	return uint64(t)
+}
+
+// The largest `r` such that `r^b <= mag`, for `b >= 2`, found by binary search
+// over the integers, so that it is exact, even when `mag` has more precision
+// than a `float64`.
+func root(mag uint64, b int64) int64 {
+	// The square root of the largest `int64`, rounded up, bounds every root
+	// when `b >= 2`.
+	lo, hi := int64(0), int64(3037000500)
+	for lo < hi {
+		mid := lo + (hi-lo+1)/2
+		if p, st := pow(mid, b); st == OK && uint64(p) <= mag {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	return lo
+}
+
+// The smallest power of 10 greater than `b`, i.e. what `a` needs to be
+// multiplied by to make room for `b`'s digits.
+func pad10(b int64) (int64, status) {
+	if b < 0 {
+		return 0, UNDEFINED
+	}
+
+	pad := int64(10)
+	for b >= pad {
+		var st status
+		if pad, st = mul(pad, 10); st != OK {
+			return 0, st
+		}
+	}
+
+	return pad, OK
+}
+
+func single(a int64, st status) ([]int64, status) {
+	if st != OK {
+		return nil, st
+	}
+	return []int64{a}, OK
+}
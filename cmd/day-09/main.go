@@ -2,12 +2,27 @@ package main
 
 import (
 	"bytes"
+	"container/heap"
+	"flag"
 	"fmt"
 	"io"
 	"os"
 )
 
+// A min-heap of offsets of free slots on disk.
+type offsets []int
+
+// The largest a single slot on disk can be.
+const MAX_SPAN = 9
+
+// Disks with more blocks than this are not rendered during simulation.
+const MAX_RENDER = 200
+
+var simulate = flag.Bool("simulate", false, "simulate compaction block by block, rendering each step for small disks, and check the checksums")
+
 func main() {
+	flag.Parse()
+
 	input := readInput(os.Stdin)
 
 	input1 := make([]int, len(input))
@@ -16,8 +31,39 @@ func main() {
 	input2 := make([]int, len(input))
 	copy(input2, input)
 
-	fmt.Println("Part 1:", part1(input1))
-	fmt.Println("Part 2:", part2(input2))
+	sum1, sum2 := part1(input1), part2(input2)
+	fmt.Println("Part 1:", sum1)
+	fmt.Println("Part 2:", sum2)
+
+	if *simulate {
+		for part, c := range []struct {
+			compact func(blocks) func(func(move) bool)
+			expect  int
+		}{
+			{blocks.compactBlocks, sum1},
+			{blocks.compactFiles, sum2},
+		} {
+			b := expand(input)
+			render := len(b) <= MAX_RENDER
+
+			fmt.Printf("Part %d simulation:\n", part+1)
+			if render {
+				fmt.Println(b)
+			}
+
+			for m := range c.compact(b) {
+				if render {
+					fmt.Printf("%v  %v\n", b, m)
+				}
+			}
+
+			if sum := b.checksum(); sum != c.expect {
+				fmt.Printf("Checksum mismatch: simulated %d, expected %d\n", sum, c.expect)
+			} else {
+				fmt.Println("Checksum:", sum)
+			}
+		}
+	}
 }
 
 func readInput(r io.Reader) []int {
@@ -84,52 +130,51 @@ func part1(disk []int) (sum int) {
 // Like `part1`, but now we can't cut up files: A file must be smaller than the
 // free slot to fill it.
 //
-// As before, free slots are still filled from low to high addresses, and the
-// file to fill it is picked from high to low addresses, but if the file does
-// not fit in the slot completely, it won't be moved.
+// Files are moved in decreasing order of file ID, each into the leftmost free
+// slot that fits it, if that slot is before the file. Free slots are indexed by
+// their size (at most 9 blocks), with a min-heap of their offsets per size, so
+// the leftmost slot that fits a file is the earliest of the heads of the heaps
+// for sizes at least as big as the file.
 func part2(disk []int) (sum int) {
-	// If the encoding includes an even number of entries, it means it ends on a
-	// free slot, which we can ignore for the purposes of calculating the
-	// checksum
-	if len(disk)%2 == 0 {
-		disk = disk[:len(disk)-1]
+	offs := make([]int, len(disk))
+	var free [MAX_SPAN + 1]offsets
+	for i, off := 0, 0; i < len(disk); i++ {
+		offs[i] = off
+		if i%2 == 1 && disk[i] > 0 {
+			// Offsets are added in increasing order, so each heap is already
+			// sorted, which means it already satisfies the heap property.
+			free[disk[i]] = append(free[disk[i]], off)
+		}
+		off += disk[i]
 	}
 
-	for written, lo := 0, 0; lo < len(disk); {
-		if lo%2 == 0 {
-			// If the lowerbound is even, then it is sitting over a file to add to
-			// the checksum. That file may have been moved to some earlier free slot,
-			// in which case it will leave a negative value behind, so detect that
-			// and skip over it.
-			if disk[lo] < 0 {
-				written -= disk[lo]
-			} else {
-				sum += checksum(written, disk[lo]) * (lo / 2)
-				written += disk[lo]
+	for i := (len(disk) - 1) &^ 1; i >= 0; i -= 2 {
+		size := disk[i]
+		if size == 0 {
+			continue
+		}
+
+		best := 0
+		for s := size; s <= MAX_SPAN; s++ {
+			if len(free[s]) == 0 || free[s][0] > offs[i] {
+				continue
 			}
-			lo++
-		} else if hi := fill(disk[lo:]); hi != 0 {
-			// If the lowerbound is odd, then it is sitting over a free slot. We need
-			// to find some later file that fits in this slot.
-			hi += lo
-			file := hi / 2
-			sum += checksum(written, disk[hi]) * file
-			written += disk[hi]
-
-			// Move the found file into the free space we are trying to fill, and
-			// update its old slot to include a sentinel value to recognise the move.
-			disk[lo] -= disk[hi]
-			disk[hi] *= -1
-
-			if disk[lo] == 0 {
-				lo++
+
+			if best == 0 || free[s][0] < free[best][0] {
+				best = s
 			}
-		} else {
-			// The lowerbound is over a free slot but we couldn't find a file to fill
-			// it, so we skip over it.
-			written += disk[lo]
-			lo++
 		}
+
+		// Move the file into the free slot, and return whatever space it did not
+		// use to the index, as a smaller slot.
+		if best != 0 {
+			offs[i] = heap.Pop(&free[best]).(int)
+			if rest := best - size; rest > 0 {
+				heap.Push(&free[rest], offs[i]+size)
+			}
+		}
+
+		sum += checksum(offs[i], size) * (i / 2)
 	}
 
 	return
@@ -139,16 +184,15 @@ func checksum(off, size int) int {
 	return size * (2*off + size - 1) / 2
 }
 
-// Assumes that `disk` starts with an empty slot, and looks for the latest slot
-// containing a file that fits in the first slot.
-//
-// Returns the index of the file that fits, or 0 if no file fits.
-func fill(disk []int) int {
-	for i := len(disk) - 1; i >= 0; i -= 2 {
-		if 0 < disk[i] && disk[i] <= disk[0] {
-			return i
-		}
-	}
-
-	return 0
+func (o offsets) Len() int           { return len(o) }
+func (o offsets) Less(i, j int) bool { return o[i] < o[j] }
+func (o offsets) Swap(i, j int)      { o[i], o[j] = o[j], o[i] }
+func (o *offsets) Push(x any)        { *o = append(*o, x.(int)) }
+
+func (o *offsets) Pop() any {
+	old := *o
+	n := len(old)
+	x := old[n-1]
+	*o = old[:n-1]
+	return x
 }
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const example = "2333133121414131402\n"
+
+func render(b blocks) string {
+	return fmt.Sprint(b)
+}
+
+func randomDisk(r *rand.Rand, n int) []int {
+	disk := make([]int, n)
+	for i := range disk {
+		disk[i] = r.Intn(10)
+	}
+
+	// The puzzle never has empty files.
+	for i := 0; i < n; i += 2 {
+		disk[i] = max(1, disk[i])
+	}
+
+	return disk
+}
+
+func TestExample(t *testing.T) {
+	disk := readInput(strings.NewReader(example))
+
+	if actual := part1(slices.Clone(disk)); actual != 1928 {
+		t.Errorf("part 1: expected 1928, got %d", actual)
+	}
+
+	if actual := part2(slices.Clone(disk)); actual != 2858 {
+		t.Errorf("part 2: expected 2858, got %d", actual)
+	}
+}
+
+func TestExpand(t *testing.T) {
+	for _, tc := range []struct {
+		disk   string
+		expect string
+	}{
+		{"12345", "0..111....22222"},
+		{example, "00...111...2...333.44.5555.6666.777.888899"},
+	} {
+		b := expand(readInput(strings.NewReader(tc.disk)))
+		if actual := render(b); actual != tc.expect {
+			t.Errorf("%s: expected %s, got %s", tc.disk, tc.expect, actual)
+		}
+	}
+}
+
+func TestCompactBlocks(t *testing.T) {
+	b := expand(readInput(strings.NewReader("12345")))
+
+	var frames []string
+	for range b.compactBlocks() {
+		frames = append(frames, render(b))
+	}
+
+	expect := []string{
+		"02.111....2222.",
+		"022111....222..",
+		"0221112...22...",
+		"02211122..2....",
+		"022111222......",
+	}
+
+	if !slices.Equal(expect, frames) {
+		t.Errorf("expected %v, got %v", expect, frames)
+	}
+}
+
+func TestCompactFiles(t *testing.T) {
+	b := expand(readInput(strings.NewReader(example)))
+
+	var frames []string
+	for range b.compactFiles() {
+		frames = append(frames, render(b))
+	}
+
+	expect := []string{
+		"0099.111...2...333.44.5555.6666.777.8888..",
+		"0099.1117772...333.44.5555.6666.....8888..",
+		"0099.111777244.333....5555.6666.....8888..",
+		"00992111777.44.333....5555.6666.....8888..",
+	}
+
+	if !slices.Equal(expect, frames) {
+		t.Errorf("expected %v, got %v", expect, frames)
+	}
+
+	if actual := b.checksum(); actual != 2858 {
+		t.Errorf("expected checksum 2858, got %d", actual)
+	}
+}
+
+func TestCompactFilesEmptyFile(t *testing.T) {
+	// File 2 has no blocks, which must not stop files 1 and 0 from moving.
+	disk := []int{1, 5, 2, 0, 0, 0, 1}
+	b := expand(disk)
+	for range b.compactFiles() {
+	}
+
+	if expect, actual := part2(slices.Clone(disk)), b.checksum(); expect != actual {
+		t.Errorf("expected checksum %d, simulated %d (%s)", expect, actual, render(b))
+	}
+
+	if actual := render(b); actual != "0311....." {
+		t.Errorf("expected 0311....., got %s", actual)
+	}
+}
+
+func TestSimulationMatchesFastPath(t *testing.T) {
+	r := rand.New(rand.NewSource(9))
+	for i := 0; i < 200; i++ {
+		disk := randomDisk(r, 1+r.Intn(60))
+
+		b1 := expand(disk)
+		for range b1.compactBlocks() {
+		}
+
+		if expect, actual := part1(slices.Clone(disk)), b1.checksum(); expect != actual {
+			t.Fatalf("part 1 %v: expected %d, simulated %d", disk, expect, actual)
+		}
+
+		b2 := expand(disk)
+		for range b2.compactFiles() {
+		}
+
+		if expect, actual := part2(slices.Clone(disk)), b2.checksum(); expect != actual {
+			t.Fatalf("part 2 %v: expected %d, simulated %d", disk, expect, actual)
+		}
+	}
+}
+
+func TestPart2MatchesScan(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	for i := 0; i < 500; i++ {
+		disk := randomDisk(r, 1+r.Intn(200))
+		if expect, actual := part2Scan(slices.Clone(disk)), part2(slices.Clone(disk)); expect != actual {
+			t.Fatalf("%v: expected %d, got %d", disk, expect, actual)
+		}
+	}
+}
+
+func BenchmarkPart2(b *testing.B) {
+	for _, n := range []int{1e4, 1e5, 1e6} {
+		disk := randomDisk(rand.New(rand.NewSource(int64(n))), n)
+
+		b.Run(fmt.Sprintf("heap/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				part2(slices.Clone(disk))
+			}
+		})
+
+		// The scan takes tens of seconds per iteration on the largest disk.
+		if n > 1e5 {
+			continue
+		}
+
+		b.Run(fmt.Sprintf("scan/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				part2Scan(slices.Clone(disk))
+			}
+		})
+	}
+}
+
+// The original implementation of `part2`, kept as a reference: For each free
+// slot, scan from the end of the disk for a file that fits, which is quadratic
+// in the size of the disk.
+//
+// As before, free slots are still filled from low to high addresses, and the
+// file to fill it is picked from high to low addresses, but if the file does
+// not fit in the slot completely, it won't be moved.
+func part2Scan(disk []int) (sum int) {
+	// If the encoding includes an even number of entries, it means it ends on a
+	// free slot, which we can ignore for the purposes of calculating the
+	// checksum
+	if len(disk)%2 == 0 {
+		disk = disk[:len(disk)-1]
+	}
+
+	for written, lo := 0, 0; lo < len(disk); {
+		if lo%2 == 0 {
+			// If the lowerbound is even, then it is sitting over a file to add to
+			// the checksum. That file may have been moved to some earlier free slot,
+			// in which case it will leave a negative value behind, so detect that
+			// and skip over it.
+			if disk[lo] < 0 {
+				written -= disk[lo]
+			} else {
+				sum += checksum(written, disk[lo]) * (lo / 2)
+				written += disk[lo]
+			}
+			lo++
+		} else if hi := fill(disk[lo:]); hi != 0 {
+			// If the lowerbound is odd, then it is sitting over a free slot. We need
+			// to find some later file that fits in this slot.
+			hi += lo
+			file := hi / 2
+			sum += checksum(written, disk[hi]) * file
+			written += disk[hi]
+
+			// Move the found file into the free space we are trying to fill, and
+			// update its old slot to include a sentinel value to recognise the move.
+			disk[lo] -= disk[hi]
+			disk[hi] *= -1
+
+			if disk[lo] == 0 {
+				lo++
+			}
+		} else {
+			// The lowerbound is over a free slot but we couldn't find a file to fill
+			// it, so we skip over it.
+			written += disk[lo]
+			lo++
+		}
+	}
+
+	return
+}
+
+// Assumes that `disk` starts with an empty slot, and looks for the latest slot
+// containing a file that fits in the first slot.
+//
+// Returns the index of the file that fits, or 0 if no file fits.
+func fill(disk []int) int {
+	for i := len(disk) - 1; i >= 0; i -= 2 {
+		if 0 < disk[i] && disk[i] <= disk[0] {
+			return i
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+)
+
+// A disk laid out block by block. Each block holds the ID of the file it
+// belongs to, or `FREE`.
+type blocks []int
+
+// A step in compacting the disk: moving `size` blocks of `file` from position
+// `from` to position `to`.
+type move struct {
+	file, from, to, size int
+}
+
+const FREE = -1
+
+// Expand the dense disk map into individual blocks.
+func expand(disk []int) (b blocks) {
+	for i, size := range disk {
+		id := FREE
+		if i%2 == 0 {
+			id = i / 2
+		}
+
+		for j := 0; j < size; j++ {
+			b = append(b, id)
+		}
+	}
+
+	return
+}
+
+func (b blocks) checksum() (sum int) {
+	for i, id := range b {
+		if id != FREE {
+			sum += i * id
+		}
+	}
+
+	return
+}
+
+// Compact the disk by moving one block at a time from the end of the disk into
+// the leftmost free block, until there are no gaps between file blocks. Each
+// move is made before it is yielded, so the disk can be inspected as it
+// changes.
+func (b blocks) compactBlocks() func(yield func(move) bool) {
+	return func(yield func(move) bool) {
+		lo, hi := 0, len(b)-1
+		for {
+			for lo < len(b) && b[lo] != FREE {
+				lo++
+			}
+
+			for hi >= 0 && b[hi] == FREE {
+				hi--
+			}
+
+			if lo >= hi {
+				return
+			}
+
+			b[lo], b[hi] = b[hi], FREE
+			if !yield(move{b[lo], hi, lo, 1}) {
+				return
+			}
+		}
+	}
+}
+
+// Compact the disk by moving whole files, in decreasing order of file ID, into
+// the leftmost span of free blocks that can fit them, if there is one to the
+// left of the file. Each move is made before it is yielded.
+func (b blocks) compactFiles() func(yield func(move) bool) {
+	return func(yield func(move) bool) {
+		// Files are only moved on their own turn, so where each one starts out
+		// is where it is when its turn comes. Files with no blocks have no span
+		// and are never moved.
+		starts, sizes := b.spans()
+		for id := len(starts) - 1; id >= 0; id-- {
+			start, size := starts[id], sizes[id]
+			if size == 0 {
+				continue
+			}
+
+			to := b.findFree(size, start)
+			if to < 0 {
+				continue
+			}
+
+			for i := 0; i < size; i++ {
+				b[to+i], b[start+i] = id, FREE
+			}
+
+			if !yield(move{id, start, to, size}) {
+				return
+			}
+		}
+	}
+}
+
+// The position of the leftmost span of at least `size` free blocks that ends
+// before `limit`, or -1 if there is none.
+func (b blocks) findFree(size, limit int) int {
+	run := 0
+	for i := 0; i < limit; i++ {
+		if b[i] != FREE {
+			run = 0
+		} else if run++; run == size {
+			return i - size + 1
+		}
+	}
+
+	return -1
+}
+
+// The position of the first block, and the number of blocks, of each file,
+// indexed by file ID. Files must be contiguous.
+func (b blocks) spans() (starts, sizes []int) {
+	n := b.maxID() + 1
+	starts, sizes = make([]int, n), make([]int, n)
+	for i, id := range b {
+		if id == FREE {
+			continue
+		}
+
+		if sizes[id] == 0 {
+			starts[id] = i
+		}
+		sizes[id]++
+	}
+
+	return
+}
+
+func (b blocks) maxID() (id int) {
+	id = FREE
+	for _, i := range b {
+		id = max(id, i)
+	}
+	return
+}
+
+// Render the disk in the style of the puzzle description: a character per
+// block, with file IDs written in base 36 (wrapping around for larger IDs),
+// and free blocks as '.'.
+func (b blocks) Format(f fmt.State, _ rune) {
+	const digits = "0123456789abcdefghijklmnopqrstuvwxyz"
+	for _, id := range b {
+		if id == FREE {
+			fmt.Fprint(f, ".")
+		} else {
+			fmt.Fprintf(f, "%c", digits[id%len(digits)])
+		}
+	}
+}
+
+func (m move) Format(f fmt.State, _ rune) {
+	fmt.Fprintf(f, "move %d block(s) of file %d from %d to %d", m.size, m.file, m.from, m.to)
+}
@@ -1,91 +1,300 @@
 package main
 
 import (
+	"cmp"
+	"flag"
 	"fmt"
 	"internal/grid"
 	"internal/point"
+	"io"
 	"os"
+	"slices"
+	"strings"
 )
 
-type exists struct{}
-type set[T comparable] map[T]exists
+type height byte
 
-type path struct {
-	from, to point.Point
+// Decides whether a hiker can step from a position at height `from` onto an
+// adjacent position at height `to`.
+type rule func(from, to height) bool
+
+// A height map, annotated with the number of distinct trails that lead from
+// each position to a summit, following some step rule.
+type trailMap struct {
+	heights *grid.Grid[height]
+	step    rule
+
+	// The number of trails from each position to a summit. Summits have a
+	// rating of one (the empty trail), and trails stop as soon as they reach a
+	// summit.
+	ratings *grid.Grid[int]
+}
+
+type trailhead struct {
+	pos     point.Point
+	score   int
+	rating  int
+	summits []point.Point
+}
+
+const (
+	TRAILHEAD height = 0
+	SUMMIT    height = 9
+
+	// Positions on the map that are not marked with a height, which can never
+	// be stepped on.
+	IMPASSABLE height = 255
+)
+
+// Sentinel ratings, used while they are being computed.
+const (
+	UNRATED = -1
+	RATING  = -2
+)
+
+// Step rules, by name. The puzzle's rule is "ascend". Ratings are only well
+// defined for rules that cannot lead a hiker around in a cycle, which holds
+// for any rule that only allows climbing.
+var RULES = map[string]rule{
+	"ascend":   func(from, to height) bool { return to == from+1 },
+	"climb":    func(from, to height) bool { return to > from },
+	"scramble": func(from, to height) bool { return to == from+1 || to == from+2 },
 }
 
+var (
+	stepRule = flag.String("rule", "ascend", "step rule to use when listing or rendering trails: ascend, climb, or scramble")
+	list     = flag.Bool("list", false, "list the score, rating and reachable summits of each trailhead")
+	trail    = flag.String("trail", "", "render a trail starting from the trailhead at `x,y`")
+	nth      = flag.Int("nth", 0, "which trail to render from the trailhead, in the order trails are enumerated")
+)
+
 func main() {
-	grid := grid.ReadFunc(os.Stdin, func(b byte) byte {
+	flag.Parse()
+
+	heights := readInput(os.Stdin)
+	fmt.Println("Part 1:", part1(heights))
+	fmt.Println("Part 2:", part2(heights))
+
+	if !*list && *trail == "" {
+		return
+	}
+
+	step, ok := RULES[*stepRule]
+	if !ok {
+		panic(fmt.Sprintf("unknown step rule %q", *stepRule))
+	}
+
+	m := newTrailMap(heights, step)
+	if *list {
+		for _, h := range m.trailheads() {
+			fmt.Println(h)
+		}
+	}
+
+	if *trail != "" {
+		var head point.Point
+		if _, err := fmt.Sscanf(*trail, "%d,%d", &head.X, &head.Y); err != nil {
+			panic(fmt.Sprintf("invalid trailhead %q: %s", *trail, err))
+		}
+
+		path := m.trail(head, *nth)
+		if path == nil {
+			fmt.Printf("No trail %d from %d,%d\n", *nth, head.X, head.Y)
+		} else {
+			fmt.Print(m.render(path))
+		}
+	}
+}
+
+func readInput(r io.Reader) *grid.Grid[height] {
+	return grid.ReadFunc(r, func(b byte) height {
 		if '0' <= b && b <= '9' {
-			return b - '0'
+			return height(b - '0')
 		} else {
-			return 255
+			return IMPASSABLE
 		}
 	})
+}
 
-	fmt.Println("Part 1:", part1(grid))
-	fmt.Println("Part 2:", part2(grid))
+func part1(g *grid.Grid[height]) (score int) {
+	m := newTrailMap(g, RULES["ascend"])
+	for x, y := range g.FindAll(TRAILHEAD) {
+		score += len(m.summits(point.New(x, y)))
+	}
+
+	return
 }
 
-func part1(g *grid.Grid[byte]) (score int) {
-	visited := make(set[path])
+func part2(g *grid.Grid[height]) (rating int) {
+	m := newTrailMap(g, RULES["ascend"])
+	for x, y := range g.FindAll(TRAILHEAD) {
+		rating += *m.ratings.Get(x, y)
+	}
 
-	var frontier []path
-	for x, y := range g.FindAll(0) {
-		origin := point.New(x, y)
-		frontier = append(frontier, path{origin, origin})
+	return
+}
+
+// Rate every position on the height map, by counting the trails from it to a
+// summit. Positions are visited in a depth-first order, so that each position
+// is rated after all the positions that can be stepped onto from it, which
+// takes time linear in the size of the map. Panics if `step` allows a hiker to
+// walk in a cycle.
+func newTrailMap(heights *grid.Grid[height], step rule) *trailMap {
+	m := &trailMap{heights, step, grid.New[int](heights.Width, heights.Height)}
+	for x, y := range m.ratings.Coords() {
+		*m.ratings.Get(x, y) = UNRATED
 	}
 
-	for len(frontier) > 0 {
-		curr := frontier[0]
-		frontier = frontier[1:]
+	for x, y := range heights.Coords() {
+		m.rate(point.New(x, y))
+	}
 
-		if _, ok := visited[curr]; ok {
-			continue
-		} else {
-			visited[curr] = exists{}
+	return m
+}
+
+func (m *trailMap) rate(p point.Point) int {
+	r := m.ratings.Get(p.X, p.Y)
+	switch *r {
+	case RATING:
+		panic(fmt.Sprintf("step rule admits a cycle through %d,%d", p.X, p.Y))
+	case UNRATED:
+		// Rated below.
+	default:
+		return *r
+	}
+
+	if *m.heights.Get(p.X, p.Y) == SUMMIT {
+		*r = 1
+		return 1
+	}
+
+	*r = RATING
+	rating := 0
+	for q := range m.next(p) {
+		rating += m.rate(q)
+	}
+
+	*r = rating
+	return rating
+}
+
+// The positions a hiker can step onto from `p`, in a fixed order.
+func (m *trailMap) next(p point.Point) func(yield func(point.Point) bool) {
+	return func(yield func(point.Point) bool) {
+		from := *m.heights.Get(p.X, p.Y)
+		if from == IMPASSABLE {
+			return
+		}
+
+		for _, dir := range []grid.Dir{grid.DIR_U, grid.DIR_R, grid.DIR_D, grid.DIR_L} {
+			x, y := dir.Move(p.X, p.Y, 1)
+			to := m.heights.Get(x, y)
+			if to == nil || *to == IMPASSABLE || !m.step(from, *to) {
+				continue
+			}
+
+			if !yield(point.New(x, y)) {
+				return
+			}
 		}
+	}
+}
 
-		pos := g.Get(curr.to.X, curr.to.Y)
-		if *pos == 9 {
-			score += 1
+// The distinct summits reachable from `p`, in reading order.
+func (m *trailMap) summits(p point.Point) (summits []point.Point) {
+	visited := map[point.Point]bool{p: true}
+	frontier := []point.Point{p}
+	for len(frontier) > 0 {
+		curr := frontier[len(frontier)-1]
+		frontier = frontier[:len(frontier)-1]
+
+		if *m.heights.Get(curr.X, curr.Y) == SUMMIT {
+			summits = append(summits, curr)
 			continue
 		}
 
-		for _, dir := range []grid.Dir{grid.DIR_U, grid.DIR_R, grid.DIR_D, grid.DIR_L} {
-			nextX, nextY := dir.Move(curr.to.X, curr.to.Y, 1)
-			if next := g.Get(nextX, nextY); next != nil && *next == *pos+1 {
-				frontier = append(frontier, path{curr.from, point.New(nextX, nextY)})
+		for next := range m.next(curr) {
+			if !visited[next] {
+				visited[next] = true
+				frontier = append(frontier, next)
 			}
 		}
 	}
 
+	slices.SortFunc(summits, func(a, b point.Point) int {
+		return cmp.Or(cmp.Compare(a.Y, b.Y), cmp.Compare(a.X, b.X))
+	})
+
 	return
 }
 
-func part2(g *grid.Grid[byte]) (rating int) {
-	var frontier []point.Point
-	for x, y := range g.FindAll(0) {
-		frontier = append(frontier, point.New(x, y))
+// Every trailhead on the map, in reading order.
+func (m *trailMap) trailheads() (heads []trailhead) {
+	for x, y := range m.heights.FindAll(TRAILHEAD) {
+		p := point.New(x, y)
+		summits := m.summits(p)
+		heads = append(heads, trailhead{p, len(summits), *m.ratings.Get(x, y), summits})
 	}
 
-	for len(frontier) > 0 {
-		curr := frontier[0]
-		frontier = frontier[1:]
+	return
+}
 
-		pos := g.Get(curr.X, curr.Y)
-		if *pos == 9 {
-			rating += 1
-			continue
-		}
+// The `n`-th trail from `p` to a summit, counting from zero, in the order
+// that trails are enumerated by taking steps in the order `next` yields them.
+// Ratings count the trails through each step, so the trail is found without
+// enumerating the trails before it. Returns `nil` if there are not that many
+// trails from `p`.
+func (m *trailMap) trail(p point.Point, n int) []point.Point {
+	if r := m.ratings.Get(p.X, p.Y); r == nil || n < 0 || n >= *r {
+		return nil
+	}
 
-		for _, dir := range []grid.Dir{grid.DIR_U, grid.DIR_R, grid.DIR_D, grid.DIR_L} {
-			nextX, nextY := dir.Move(curr.X, curr.Y, 1)
-			if next := g.Get(nextX, nextY); next != nil && *next == *pos+1 {
-				frontier = append(frontier, point.New(nextX, nextY))
+	path := []point.Point{p}
+	for *m.heights.Get(p.X, p.Y) != SUMMIT {
+		for q := range m.next(p) {
+			if r := *m.ratings.Get(q.X, q.Y); n >= r {
+				n -= r
+			} else {
+				p = q
+				break
 			}
 		}
+
+		path = append(path, p)
 	}
 
-	return
+	return path
+}
+
+// Render `path` on the height map, in the style of the puzzle description,
+// showing only the heights along the path.
+func (m *trailMap) render(path []point.Point) *grid.Grid[height] {
+	g := grid.New[height](m.heights.Width, m.heights.Height)
+	for x, y := range g.Coords() {
+		*g.Get(x, y) = IMPASSABLE
+	}
+
+	for _, p := range path {
+		*g.Get(p.X, p.Y) = *m.heights.Get(p.X, p.Y)
+	}
+
+	return g
+}
+
+func (h height) Format(f fmt.State, _ rune) {
+	if h == IMPASSABLE {
+		fmt.Fprint(f, ".")
+	} else {
+		fmt.Fprintf(f, "%d", byte(h))
+	}
+}
+
+func (t trailhead) Format(f fmt.State, _ rune) {
+	summits := make([]string, len(t.summits))
+	for i, s := range t.summits {
+		summits[i] = fmt.Sprintf("%d,%d", s.X, s.Y)
+	}
+
+	fmt.Fprintf(f, "%d,%d: score %d, rating %d, summits %s",
+		t.pos.X, t.pos.Y, t.score, t.rating, strings.Join(summits, " "))
 }
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"internal/grid"
+	"internal/point"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+const example = `89010123
+78121874
+87430965
+96549874
+45678903
+32019012
+01329801
+10456732
+`
+
+// Count trails by walking every one of them, breadth first.
+func naiveRatings(g *grid.Grid[height]) (rating int) {
+	var frontier []point.Point
+	for x, y := range g.FindAll(TRAILHEAD) {
+		frontier = append(frontier, point.New(x, y))
+	}
+
+	for len(frontier) > 0 {
+		curr := frontier[0]
+		frontier = frontier[1:]
+
+		pos := g.Get(curr.X, curr.Y)
+		if *pos == SUMMIT {
+			rating += 1
+			continue
+		}
+
+		for _, dir := range []grid.Dir{grid.DIR_U, grid.DIR_R, grid.DIR_D, grid.DIR_L} {
+			nextX, nextY := dir.Move(curr.X, curr.Y, 1)
+			if next := g.Get(nextX, nextY); next != nil && *next == *pos+1 {
+				frontier = append(frontier, point.New(nextX, nextY))
+			}
+		}
+	}
+
+	return
+}
+
+func randomMap(r *rand.Rand, w, h int) *grid.Grid[height] {
+	g := grid.New[height](w, h)
+	for x, y := range g.Coords() {
+		*g.Get(x, y) = height(r.Intn(10))
+	}
+
+	return g
+}
+
+func TestExample(t *testing.T) {
+	g := readInput(strings.NewReader(example))
+
+	if actual := part1(g); actual != 36 {
+		t.Errorf("part 1: expected 36, got %d", actual)
+	}
+
+	if actual := part2(g); actual != 81 {
+		t.Errorf("part 2: expected 81, got %d", actual)
+	}
+}
+
+func TestTrailheads(t *testing.T) {
+	m := newTrailMap(readInput(strings.NewReader(example)), RULES["ascend"])
+	heads := m.trailheads()
+
+	expect := []int{5, 6, 5, 3, 1, 3, 5, 3, 5}
+	if len(heads) != len(expect) {
+		t.Fatalf("expected %d trailheads, got %d", len(expect), len(heads))
+	}
+
+	for i, h := range heads {
+		if h.score != expect[i] || len(h.summits) != h.score {
+			t.Errorf("%v: expected score %d", h, expect[i])
+		}
+	}
+
+	if actual := fmt.Sprint(heads[0]); actual != "2,0: score 5, rating 20, summits 1,0 0,3 4,3 5,4 4,5" {
+		t.Errorf("unexpected trailhead: %s", actual)
+	}
+}
+
+func TestTrails(t *testing.T) {
+	m := newTrailMap(readInput(strings.NewReader(example)), RULES["ascend"])
+	for _, h := range m.trailheads() {
+		seen := make(map[string]bool)
+		for n := 0; n < h.rating; n++ {
+			path := m.trail(h.pos, n)
+			if len(path) != 10 {
+				t.Fatalf("%v: trail %d has %d steps", h.pos, n, len(path))
+			}
+
+			for i, p := range path {
+				if actual := *m.heights.Get(p.X, p.Y); actual != height(i) {
+					t.Fatalf("%v: trail %d at height %d on step %d", h.pos, n, actual, i)
+				}
+			}
+
+			seen[fmt.Sprint(path)] = true
+		}
+
+		if len(seen) != h.rating {
+			t.Errorf("%v: expected %d distinct trails, got %d", h.pos, h.rating, len(seen))
+		}
+
+		if m.trail(h.pos, h.rating) != nil {
+			t.Errorf("%v: expected no trail %d", h.pos, h.rating)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	m := newTrailMap(readInput(strings.NewReader(example)), RULES["ascend"])
+	actual := fmt.Sprint(m.render(m.trail(point.New(2, 0), 3)))
+	expect := strings.Join([]string{
+		". 9 0 1 . . . . ",
+		". 8 . 2 . . . . ",
+		". 7 . 3 . . . . ",
+		". 6 5 4 . . . . ",
+		". . . . . . . . ",
+		". . . . . . . . ",
+		". . . . . . . . ",
+		". . . . . . . . ",
+	}, "\n") + "\n"
+
+	if actual != expect {
+		t.Errorf("expected:\n%s\ngot:\n%s", expect, actual)
+	}
+}
+
+func TestRatingsMatchNaive(t *testing.T) {
+	r := rand.New(rand.NewSource(10))
+	for i := 0; i < 100; i++ {
+		g := randomMap(r, 1+r.Intn(12), 1+r.Intn(12))
+		if expect, actual := naiveRatings(g), part2(g); expect != actual {
+			t.Fatalf("expected %d, got %d for:\n%v", expect, actual, g)
+		}
+	}
+}
+
+func TestCyclicRule(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected a panic")
+		}
+	}()
+
+	level := func(from, to height) bool { return from == to }
+	newTrailMap(readInput(strings.NewReader("00\n")), level)
+}
@@ -1,95 +1,259 @@
 package main
 
 import (
+	"cmp"
+	"flag"
 	"fmt"
 	"io"
+	"maps"
+	"math"
 	"math/big"
 	"os"
+	"slices"
+	"strings"
 )
 
-type stone struct {
-	engraving  string
-	generation int
+// A multiset of stones, counted by the number engraved on them. Most
+// engravings fit in a native integer, and the few that don't are kept
+// separately as `big.Int`s, keyed by their decimal representation.
+type stones struct {
+	small map[uint64]int
+	large map[string]*largeStone
 }
 
-func main() {
-	input := readInput(io.Reader(os.Stdin))
+type largeStone struct {
+	engraving *big.Int
+	count     int
+}
+
+// How many stones bear a particular engraving.
+type entry struct {
+	engraving string
+	count     int
+}
+
+// A summary of the stones after some number of blinks.
+type distribution struct {
+	blink    int
+	total    int
+	distinct int
+
+	// The most common engravings, most common first.
+	common []entry
+}
+
+var (
+	BIG_10   = big.NewInt(10)
+	BIG_2024 = big.NewInt(2024)
+)
+
+// Powers of ten that fit in a `uint64`, used to split engravings in half.
+var POW10 = func() (pow [20]uint64) {
+	pow[0] = 1
+	for i := 1; i < len(pow); i++ {
+		pow[i] = pow[i-1] * 10
+	}
+	return
+}()
 
-	input1 := make([]big.Int, len(input))
-	copy(input1, input)
+var (
+	report = flag.Bool("distribution", false, "print the distribution of stones after each blink")
+	blinks = flag.Int("blinks", 75, "number of blinks to report the distribution for")
+	top    = flag.Int("top", 3, "number of most common stones to include in each distribution")
+)
+
+func main() {
+	flag.Parse()
 
-	input2 := make([]big.Int, len(input))
-	copy(input2, input)
+	input := readInput(os.Stdin)
+	fmt.Println("Part 1:", simulate(input, 25))
+	fmt.Println("Part 2:", simulate(input, 75))
 
-	fmt.Println("Part 1:", simulate(input1, 25))
-	fmt.Println("Part 2:", simulate(input2, 75))
+	if *report {
+		for d := range distributions(input, *blinks, *top) {
+			fmt.Println(d)
+		}
+	}
 }
 
-func readInput(r io.Reader) []big.Int {
-	input := make([]big.Int, 0)
+func readInput(r io.Reader) *stones {
+	s := newStones()
 
-	var buf int64
 	for {
-		_, err := fmt.Fscanf(r, "%d", &buf)
-		if err != nil {
+		var n big.Int
+		if _, err := fmt.Fscan(r, &n); err != nil {
 			break
 		}
 
-		input = append(input, *big.NewInt(buf))
+		s.addBig(&n, 1)
 	}
 
-	return input
+	return s
 }
 
-func simulate(input []big.Int, reps int) (total int) {
-	var (
-		bi0    = big.NewInt(int64(0))
-		bi1    = big.NewInt(int64(1))
-		bi10   = big.NewInt(int64(10))
-		bi2024 = big.NewInt(int64(2024))
-	)
+func simulate(input *stones, reps int) int {
+	s := input
+	for i := 0; i < reps; i++ {
+		s = s.blink()
+	}
 
-	cache := make(map[stone]int)
-	var count func(*big.Int, int) int
-	count = func(engraving *big.Int, reps int) int {
-		if reps <= 0 {
-			return 1
-		}
+	return s.total()
+}
 
-		key := stone{engraving.Text(10), reps}
-		if v, ok := cache[key]; ok {
-			return v
+// Summarise the stones after each of the first `reps` blinks, including the
+// `top` most common stones.
+func distributions(input *stones, reps, top int) func(yield func(distribution) bool) {
+	return func(yield func(distribution) bool) {
+		s := input
+		for i := 1; i <= reps; i++ {
+			s = s.blink()
+			if !yield(distribution{i, s.total(), s.distinct(), s.common(top)}) {
+				return
+			}
 		}
+	}
+}
+
+func newStones() *stones {
+	return &stones{make(map[uint64]int), make(map[string]*largeStone)}
+}
+
+// Add `count` stones engraved with `n`.
+func (s *stones) add(n uint64, count int) {
+	s.small[n] = addCount(s.small[n], count)
+}
+
+// Add `count` stones engraved with `n`, which may or may not fit in a native
+// integer.
+func (s *stones) addBig(n *big.Int, count int) {
+	if n.IsUint64() {
+		s.add(n.Uint64(), count)
+		return
+	}
+
+	key := n.Text(10)
+	if l, ok := s.large[key]; ok {
+		l.count = addCount(l.count, count)
+	} else {
+		s.large[key] = &largeStone{n, count}
+	}
+}
+
+// The stones after blinking once. Every stone changes simultaneously,
+// according to the first rule that applies to it.
+func (s *stones) blink() *stones {
+	next := newStones()
 
-		if engraving.Cmp(bi0) == 0 {
+	for n, count := range s.small {
+		if n == 0 {
 			// If the stone is engraved with the number 0, it is replaced by a
 			// stone engraved with the number `1`.
-			cache[key] = count(bi1, reps-1)
-		} else if digits := len(key.engraving); digits%2 == 0 {
+			next.add(1, count)
+		} else if digits := digits(n); digits%2 == 0 {
 			// If the stone is engraved with a number that has an even number of
 			// digits, it is replaced by two stones. The left half of the digits
 			// are engraved on the new left stone, and the right half of the digits
 			// are engraved on the new right stone. (The new numbers don't keep
 			// extra leading zeroes: 1000 would become stones 10 and 0.)
-			var l, r big.Int
-			l.Exp(bi10, big.NewInt(int64(digits/2)), nil)
-			l.DivMod(engraving, &l, &r)
-			cache[key] = count(&l, reps-1) + count(&r, reps-1)
-		} else {
+			next.add(n/POW10[digits/2], count)
+			next.add(n%POW10[digits/2], count)
+		} else if n <= math.MaxUint64/2024 {
 			// If none of the other rules apply, the stone is replaced by a new
 			// stone; the old stone's number multiplied by 2024 is engraved on the
 			// new stone.
-			var n big.Int
-			n.Mul(engraving, bi2024)
-			cache[key] = count(&n, reps-1)
+			next.add(n*2024, count)
+		} else {
+			// The product does not fit in a native integer, so fall back to
+			// computing it as a `big.Int`.
+			var m big.Int
+			m.SetUint64(n)
+			next.addBig(m.Mul(&m, BIG_2024), count)
+		}
+	}
+
+	// Large stones are never engraved with 0, so only the latter two rules
+	// apply to them.
+	for key, l := range s.large {
+		if digits := len(key); digits%2 == 0 {
+			var hi, lo, pow big.Int
+			pow.Exp(BIG_10, big.NewInt(int64(digits/2)), nil)
+			hi.DivMod(l.engraving, &pow, &lo)
+			next.addBig(&hi, l.count)
+			next.addBig(&lo, l.count)
+		} else {
+			var m big.Int
+			next.addBig(m.Mul(l.engraving, BIG_2024), l.count)
 		}
+	}
+
+	return next
+}
 
-		return cache[key]
+// The number of stones in the multiset.
+func (s *stones) total() (total int) {
+	for _, count := range s.small {
+		total = addCount(total, count)
 	}
 
-	for _, n := range input {
-		total += count(&n, reps)
+	for _, l := range s.large {
+		total = addCount(total, l.count)
 	}
 
 	return
 }
+
+// The number of distinct engravings in the multiset.
+func (s *stones) distinct() int {
+	return len(s.small) + len(s.large)
+}
+
+// The `n` most common engravings, breaking ties in favour of smaller
+// engravings.
+func (s *stones) common(n int) []entry {
+	entries := make([]entry, 0, s.distinct())
+	for _, k := range slices.Sorted(maps.Keys(s.small)) {
+		entries = append(entries, entry{fmt.Sprint(k), s.small[k]})
+	}
+
+	// Large engravings are all bigger than small ones, and among themselves
+	// they can be ordered by the length of their representation first.
+	large := slices.SortedFunc(maps.Keys(s.large), func(a, b string) int {
+		return cmp.Or(cmp.Compare(len(a), len(b)), cmp.Compare(a, b))
+	})
+
+	for _, k := range large {
+		entries = append(entries, entry{k, s.large[k].count})
+	}
+
+	slices.SortStableFunc(entries, func(a, b entry) int {
+		return cmp.Compare(b.count, a.count)
+	})
+
+	return entries[:min(n, len(entries))]
+}
+
+// The number of decimal digits in `n`.
+func digits(n uint64) (d int) {
+	for d = 1; d < len(POW10) && n >= POW10[d]; d++ {
+	}
+	return
+}
+
+// Add two (non-negative) counts of stones, panicking if the sum overflows.
+func addCount(a, b int) int {
+	if a > math.MaxInt-b {
+		panic("too many stones to count")
+	}
+
+	return a + b
+}
+
+func (d distribution) Format(f fmt.State, _ rune) {
+	common := make([]string, len(d.common))
+	for i, e := range d.common {
+		common[i] = fmt.Sprintf("%s (x%d)", e.engraving, e.count)
+	}
+
+	fmt.Fprintf(f, "Blink %d: %d stones, %d distinct, most common %s",
+		d.blink, d.total, d.distinct, strings.Join(common, ", "))
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// Count the stones by blinking at each one individually, using `big.Int`s
+// throughout.
+func naiveCount(engraving *big.Int, reps int) int {
+	if reps == 0 {
+		return 1
+	}
+
+	text := engraving.Text(10)
+	if engraving.Sign() == 0 {
+		return naiveCount(big.NewInt(1), reps-1)
+	} else if len(text)%2 == 0 {
+		var hi, lo big.Int
+		hi.SetString(text[:len(text)/2], 10)
+		lo.SetString(text[len(text)/2:], 10)
+		return naiveCount(&hi, reps-1) + naiveCount(&lo, reps-1)
+	} else {
+		var n big.Int
+		return naiveCount(n.Mul(engraving, BIG_2024), reps-1)
+	}
+}
+
+func TestExample(t *testing.T) {
+	input := readInput(strings.NewReader("125 17\n"))
+
+	for _, tc := range []struct {
+		reps, expect int
+	}{
+		{6, 22},
+		{25, 55312},
+	} {
+		if actual := simulate(input, tc.reps); actual != tc.expect {
+			t.Errorf("%d blinks: expected %d, got %d", tc.reps, tc.expect, actual)
+		}
+	}
+}
+
+func TestDistribution(t *testing.T) {
+	input := readInput(strings.NewReader("0 1 10 99 999\n"))
+
+	var actual []string
+	for d := range distributions(input, 1, 2) {
+		actual = append(actual, fmt.Sprint(d))
+	}
+
+	expect := "Blink 1: 7 stones, 5 distinct, most common 1 (x2), 9 (x2)"
+	if len(actual) != 1 || actual[0] != expect {
+		t.Errorf("expected %q, got %q", expect, actual)
+	}
+}
+
+func TestLargeStones(t *testing.T) {
+	// Both stones overflow a `uint64` after their first blink, and split back
+	// into native stones later.
+	input := readInput(strings.NewReader("9999999999999999999 1234567890123456789\n"))
+
+	s := input.blink()
+	if len(s.small) != 0 || len(s.large) != 2 {
+		t.Fatalf("expected only large stones, got %v small and %v large", s.small, s.large)
+	}
+
+	for reps := 0; reps <= 12; reps++ {
+		expect := 0
+		for _, n := range []string{"9999999999999999999", "1234567890123456789"} {
+			var e big.Int
+			e.SetString(n, 10)
+			expect += naiveCount(&e, reps)
+		}
+
+		if actual := simulate(input, reps); actual != expect {
+			t.Errorf("%d blinks: expected %d, got %d", reps, expect, actual)
+		}
+	}
+}
+
+func TestMatchesNaive(t *testing.T) {
+	r := rand.New(rand.NewSource(11))
+	for i := 0; i < 50; i++ {
+		n := big.NewInt(r.Int63n(1_000_000_000))
+		input := readInput(strings.NewReader(n.Text(10)))
+
+		reps := r.Intn(20)
+		if expect, actual := naiveCount(n, reps), simulate(input, reps); expect != actual {
+			t.Errorf("%v after %d blinks: expected %d, got %d", n, reps, expect, actual)
+		}
+	}
+}
+
+func TestCommonOrder(t *testing.T) {
+	s := newStones()
+	s.add(7, 3)
+	s.add(5, 3)
+	s.add(100, 4)
+
+	var large big.Int
+	large.SetString("123456789012345678901", 10)
+	s.addBig(&large, 3)
+
+	expect := []entry{{"100", 4}, {"5", 3}, {"7", 3}, {"123456789012345678901", 3}}
+	actual := s.common(10)
+	if fmt.Sprint(expect) != fmt.Sprint(actual) {
+		t.Errorf("expected %v, got %v", expect, actual)
+	}
+}
@@ -1,21 +1,48 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"internal/grid"
 	"internal/point"
+	"maps"
 	"os"
+	"slices"
 )
 
 type field struct {
 	area, perimeter, corner int
 }
 
+var (
+	svg      = flag.Bool("svg", false, "render the garden and its fences as an SVG instead of solving")
+	outlines = flag.Bool("outlines", false, "list the corners of the fence around each region")
+)
+
 func main() {
+	flag.Parse()
+
 	grid := grid.ReadBytes(os.Stdin)
 	renumbered := renumber(grid)
+	if *svg {
+		writeSVG(os.Stdout, grid, renumbered)
+		return
+	}
+
 	fmt.Println("Part 1:", part1(renumbered))
 	fmt.Println("Part 2:", part2(renumbered))
+
+	if *outlines {
+		traced := traceOutlines(renumbered)
+		for _, id := range slices.Sorted(maps.Keys(traced)) {
+			o := traced[id]
+			fmt.Printf("%c: area %d, sides %d\n", *grid.Get(o.outer[0].X, o.outer[0].Y), o.area(), o.sides())
+			fmt.Printf("  outer: %v\n", o.outer)
+			for _, h := range o.holes {
+				fmt.Printf("  hole:  %v\n", h)
+			}
+		}
+	}
 }
 
 func part1(g *grid.Grid[int]) (cost int) {
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"internal/grid"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+const example = `RRRRIICCFF
+RRRRIICCCF
+VVRRRCCFFF
+VVRCCCJFFF
+VVVVCJJCFE
+VVIVCCJJEE
+VVIIICJJEE
+MIIIIIJJEE
+MIIISIJEEE
+MMMISSJEEE
+`
+
+func readGarden(s string) *grid.Grid[int] {
+	return renumber(grid.ReadBytes(strings.NewReader(s)))
+}
+
+func TestExamples(t *testing.T) {
+	for _, tc := range []struct {
+		garden       string
+		part1, part2 int
+	}{
+		{"AAAA\nBBCD\nBBCC\nEEEC\n", 140, 80},
+		{"OOOOO\nOXOXO\nOOOOO\nOXOXO\nOOOOO\n", 772, 436},
+		{"EEEEE\nEXXXX\nEEEEE\nEXXXX\nEEEEE\n", 692, 236},
+		{"AAAAAA\nAAABBA\nAAABBA\nABBAAA\nABBAAA\nAAAAAA\n", 1184, 368},
+		{example, 1930, 1206},
+	} {
+		g := readGarden(tc.garden)
+		if actual := part1(g); actual != tc.part1 {
+			t.Errorf("part 1: expected %d, got %d for:\n%s", tc.part1, actual, tc.garden)
+		}
+
+		if actual := part2(g); actual != tc.part2 {
+			t.Errorf("part 2: expected %d, got %d for:\n%s", tc.part2, actual, tc.garden)
+		}
+	}
+}
+
+func TestHoles(t *testing.T) {
+	g := readGarden("OOOOO\nOXOXO\nOOOOO\nOXOXO\nOOOOO\n")
+	o := traceOutlines(g)[*g.Get(0, 0)]
+
+	if actual := fmt.Sprint(o.outer); actual != "0,0 5,0 5,5 0,5" {
+		t.Errorf("unexpected outer ring: %s", actual)
+	}
+
+	expect := []string{"1,1 1,2 2,2 2,1", "3,1 3,2 4,2 4,1", "1,3 1,4 2,4 2,3", "3,3 3,4 4,4 4,3"}
+	if actual := fmt.Sprint(o.holes); actual != fmt.Sprint(expect) {
+		t.Errorf("expected holes %v, got %v", expect, actual)
+	}
+}
+
+func TestDiagonalHoles(t *testing.T) {
+	// The two B regions touch diagonally inside A, but they are separate
+	// regions, so they need separate holes.
+	g := readGarden("AAAAAA\nAAABBA\nAAABBA\nABBAAA\nABBAAA\nAAAAAA\n")
+	o := traceOutlines(g)[*g.Get(0, 0)]
+
+	expect := []string{"3,1 3,3 5,3 5,1", "1,3 1,5 3,5 3,3"}
+	if actual := fmt.Sprint(o.holes); actual != fmt.Sprint(expect) {
+		t.Errorf("expected holes %v, got %v", expect, actual)
+	}
+}
+
+func TestOutlinesMatchSurvey(t *testing.T) {
+	r := rand.New(rand.NewSource(12))
+	for i := 0; i < 200; i++ {
+		w, h := 1+r.Intn(12), 1+r.Intn(12)
+
+		var b strings.Builder
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				b.WriteByte(byte('A' + r.Intn(3)))
+			}
+			b.WriteByte('\n')
+		}
+
+		g := readGarden(b.String())
+		fields, outlines := surveyFields(g), traceOutlines(g)
+		if len(fields) != len(outlines) {
+			t.Fatalf("expected %d outlines, got %d for:\n%s", len(fields), len(outlines), b.String())
+		}
+
+		for id, f := range fields {
+			o := outlines[id]
+			if o.area() != f.area || o.sides() != f.corner {
+				t.Fatalf("region %d: expected area %d and %d corners, got %d and %d for:\n%s",
+					id, f.area, f.corner, o.area(), o.sides(), b.String())
+			}
+
+			perimeter := 0
+			for _, r := range append([]ring{o.outer}, o.holes...) {
+				for i, p := range r {
+					q := r[(i+1)%len(r)]
+					perimeter += max(p.X-q.X, q.X-p.X) + max(p.Y-q.Y, q.Y-p.Y)
+				}
+			}
+
+			if perimeter != f.perimeter {
+				t.Fatalf("region %d: expected perimeter %d, got %d", id, f.perimeter, perimeter)
+			}
+		}
+	}
+}
+
+func TestSVG(t *testing.T) {
+	plants := grid.ReadBytes(strings.NewReader("AAA\nABA\nAAA\n"))
+
+	var b strings.Builder
+	writeSVG(&b, plants, renumber(plants))
+
+	for _, expect := range []string{
+		`<path d="M0 0 L30 0 L30 30 L0 30 Z M10 10 L10 20 L20 20 L20 10 Z"`,
+		`<title>A: area 8, sides 8</title>`,
+		`<path d="M10 10 L20 10 L20 20 L10 20 Z"`,
+		`<title>B: area 1, sides 4</title>`,
+	} {
+		if !strings.Contains(b.String(), expect) {
+			t.Errorf("expected SVG to contain %s, got:\n%s", expect, b.String())
+		}
+	}
+}
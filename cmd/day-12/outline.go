@@ -0,0 +1,195 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"internal/grid"
+	"internal/point"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// A closed loop of fence, as the positions of its corners, on the lattice
+// between garden plots: Corner (x, y) is the top-left corner of plot (x, y).
+// Rings are oriented so that the region they enclose is on their right, which
+// makes outer boundaries run clockwise and holes run anti-clockwise (on
+// screen, where y grows downwards).
+type ring []point.Point
+
+// The fence around a region: its outer boundary, and the boundaries of any
+// holes in it, where other regions are enclosed.
+type outline struct {
+	outer ring
+	holes []ring
+}
+
+// The size of each plot in a rendered SVG.
+const PLOT_SIZE = 10
+
+// Trace the fences around every region in `g`, keyed by region.
+//
+// The fence is made of unit edges along the sides of plots that border a
+// different region (or the edge of the garden). Edges are directed to keep
+// their region on the right, so every corner has as many edges leaving it as
+// entering it, and they can be followed into rings. Where a region touches
+// itself diagonally, two edges leave the same corner, and the ring turns left,
+// joining the two plots. This keeps the plots on the other diagonal apart,
+// which agrees with regions only connecting orthogonally, so that every region
+// enclosed by another gets its own hole.
+func traceOutlines(g *grid.Grid[int]) map[int]outline {
+	edges := make(map[int]map[point.Point]grid.Dir)
+	for x, y := range g.Coords() {
+		id := *g.Get(x, y)
+		if edges[id] == nil {
+			edges[id] = make(map[point.Point]grid.Dir)
+		}
+
+		for _, side := range []struct {
+			dir  grid.Dir
+			from point.Point
+			to   grid.Dir
+		}{
+			{grid.DIR_U, point.New(x, y), grid.DIR_R},
+			{grid.DIR_R, point.New(x+1, y), grid.DIR_D},
+			{grid.DIR_D, point.New(x+1, y+1), grid.DIR_L},
+			{grid.DIR_L, point.New(x, y+1), grid.DIR_U},
+		} {
+			nbrX, nbrY := side.dir.Move(x, y, 1)
+			if nbr := g.Get(nbrX, nbrY); nbr == nil || *nbr != id {
+				edges[id][side.from] |= side.to
+			}
+		}
+	}
+
+	outlines := make(map[int]outline)
+	for id, out := range edges {
+		// Each ring starts from the first corner in reading order that still
+		// has an edge leaving it. Only one edge can leave such a corner, and
+		// the fence always turns there, so it is one of the ring's vertices.
+		corners := slices.SortedFunc(maps.Keys(out), readingOrder)
+
+		var o outline
+		for _, start := range corners {
+			if out[start] == 0 {
+				continue
+			}
+
+			r := traceRing(out, start)
+			if r.area() < 0 {
+				o.holes = append(o.holes, r)
+			} else if o.outer == nil {
+				o.outer = r
+			} else {
+				panic(fmt.Sprintf("region %d has more than one outer boundary", id))
+			}
+		}
+
+		outlines[id] = o
+	}
+
+	return outlines
+}
+
+// Follow edges from `start` until they return to it, consuming them, and
+// returning the corners where the ring turns.
+func traceRing(out map[point.Point]grid.Dir, start point.Point) (r ring) {
+	curr, heading := start, out[start]
+	for {
+		next := heading
+		for _, turn := range []grid.Dir{heading.RotateCounterClockwise(), heading, heading.RotateClockwise()} {
+			if out[curr]&turn != 0 {
+				next = turn
+				break
+			}
+		}
+
+		if next != heading || len(r) == 0 {
+			r = append(r, curr)
+		}
+
+		out[curr] &^= next
+		heading = next
+
+		x, y := next.Move(curr.X, curr.Y, 1)
+		if curr = point.New(x, y); curr == start {
+			return
+		}
+	}
+}
+
+// The area enclosed by the ring, which is positive for outer boundaries and
+// negative for holes.
+func (r ring) area() (area int) {
+	for i, p := range r {
+		q := r[(i+1)%len(r)]
+		area += p.X*q.Y - q.X*p.Y
+	}
+
+	return area / 2
+}
+
+// The number of straight sections of fence around the region, which is the
+// same as the number of corners on its rings.
+func (o outline) sides() (sides int) {
+	sides = len(o.outer)
+	for _, h := range o.holes {
+		sides += len(h)
+	}
+	return
+}
+
+// The number of plots in the region.
+func (o outline) area() (area int) {
+	area = o.outer.area()
+	for _, h := range o.holes {
+		area += h.area()
+	}
+	return
+}
+
+// Render the garden as an SVG, with each region filled in a colour picked by
+// its plant type, and its fence drawn around it.
+func writeSVG(w io.Writer, plants *grid.Grid[byte], regions *grid.Grid[int]) {
+	fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="-1 -1 %d %d">`+"\n",
+		plants.Width*PLOT_SIZE+2, plants.Height*PLOT_SIZE+2)
+
+	outlines := traceOutlines(regions)
+	for _, id := range slices.Sorted(maps.Keys(outlines)) {
+		o := outlines[id]
+		x, y := o.outer[0].X, o.outer[0].Y
+		plant := *plants.Get(x, y)
+
+		var d strings.Builder
+		for _, r := range append([]ring{o.outer}, o.holes...) {
+			for i, p := range r {
+				cmd := 'L'
+				if i == 0 {
+					cmd = 'M'
+				}
+				fmt.Fprintf(&d, "%c%d %d ", cmd, p.X*PLOT_SIZE, p.Y*PLOT_SIZE)
+			}
+			d.WriteString("Z ")
+		}
+
+		fmt.Fprintf(w, `  <path d="%s" fill="hsl(%d, 60%%, 75%%)" fill-rule="evenodd" stroke="black" stroke-width="1">`,
+			strings.TrimSpace(d.String()), int(plant)*137%360)
+		fmt.Fprintf(w, "<title>%c: area %d, sides %d</title></path>\n", plant, o.area(), o.sides())
+	}
+
+	fmt.Fprintln(w, "</svg>")
+}
+
+func (r ring) Format(f fmt.State, _ rune) {
+	for i, p := range r {
+		if i > 0 {
+			fmt.Fprint(f, " ")
+		}
+		fmt.Fprintf(f, "%d,%d", p.X, p.Y)
+	}
+}
+
+func readingOrder(a, b point.Point) int {
+	return cmp.Or(cmp.Compare(a.Y, b.Y), cmp.Compare(a.X, b.X))
+}
@@ -1,93 +1,119 @@
 package main
 
 import (
+	"bufio"
+	"flag"
 	"fmt"
+	"internal/linalg"
+	"internal/point"
 	"io"
 	"os"
+	"strconv"
+	"strings"
 )
 
 type machine struct {
-	ax, ay, bx, by, x, y int
+	buttons []point.Vec
+	prize   point.Point
 }
 
-const (
-	A_COST = 3
-	B_COST = 1
-)
+// How far the prizes really are, in part 2.
+const OFFSET = 10000000000000
 
-func main() {
-	input := readInput(os.Stdin)
+var costs = flag.String("costs", "3,1", "comma-separated cost of pressing each button, in the order they are listed")
 
-	input1 := make([]machine, len(input))
-	copy(input1, input)
+func main() {
+	flag.Parse()
 
-	input2 := make([]machine, len(input))
-	copy(input2, input)
+	var cost []int
+	for _, c := range strings.Split(*costs, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(c))
+		if err != nil {
+			panic(fmt.Sprintf("invalid cost %q", c))
+		}
+		cost = append(cost, n)
+	}
 
-	fmt.Println("Part 1:", part1(input1))
-	fmt.Println("Part 2:", part2(input2))
+	input := readInput(os.Stdin)
+	fmt.Println("Part 1:", part1(input, cost))
+	fmt.Println("Part 2:", part2(input, cost))
 }
 
-func part1(input []machine) (total int) {
+func part1(input []machine, cost []int) (total int) {
 	for _, m := range input {
-		if cost, ok := solve(m); ok {
-			total += cost
+		if tokens, ok := solve(m, cost); ok {
+			total += tokens
 		}
 	}
 	return
 }
 
-func part2(input []machine) (total int) {
+func part2(input []machine, cost []int) (total int) {
 	for _, m := range input {
-		m.x += 10000000000000
-		m.y += 10000000000000
-		if cost, ok := solve(m); ok {
-			total += cost
+		m.prize.X += OFFSET
+		m.prize.Y += OFFSET
+		if tokens, ok := solve(m, cost); ok {
+			total += tokens
 		}
 	}
 	return
 }
 
-func solve(m machine) (cost int, soluble bool) {
-	det := m.ax*m.by - m.ay*m.bx
-	if det == 0 {
-		return
+// The fewest tokens needed to win the prize on machine `m`, where pressing the
+// i-th button costs `cost[i]` tokens, and whether it can be won at all. When
+// buttons move the claw in the same direction, there can be many ways to win,
+// and the cheapest is chosen.
+func solve(m machine, cost []int) (tokens int, soluble bool) {
+	if len(cost) < len(m.buttons) {
+		panic(fmt.Sprintf("no cost for button %d", len(cost)))
+	}
+
+	a := [][]int{make([]int, len(m.buttons)), make([]int, len(m.buttons))}
+	for i, b := range m.buttons {
+		a[0][i], a[1][i] = b.Dx, b.Dy
 	}
 
-	a := m.by*m.x - m.bx*m.y
-	b := m.ax*m.y - m.ay*m.x
+	l, ok := linalg.Solve(a, []int{m.prize.X, m.prize.Y})
+	if !ok {
+		return
+	}
 
-	if a%det != 0 || b%det != 0 {
+	_, total, ok := l.MinCost(cost[:len(m.buttons)])
+	if !ok {
 		return
 	}
 
-	soluble = true
-	cost = A_COST*(a/det) + B_COST*(b/det)
-	return
-}
+	if !total.IsInt64() {
+		panic(fmt.Sprintf("prize costs too many tokens: %s", total))
+	}
 
-func readInput(r io.Reader) []machine {
-	input := make([]machine, 0)
+	return int(total.Int64()), true
+}
 
+// Machines are separated by blank lines, and each lists its buttons (any
+// number of them) followed by its prize.
+func readInput(r io.Reader) (input []machine) {
 	var buf machine
-	for {
-		if _, err := fmt.Fscanf(r, "Button A: X+%d, Y+%d\n", &buf.ax, &buf.ay); err != nil {
-			break
+	for s := bufio.NewScanner(r); s.Scan(); {
+		line := s.Text()
+		if line == "" {
+			continue
 		}
 
-		if _, err := fmt.Fscanf(r, "Button B: X+%d, Y+%d\n", &buf.bx, &buf.by); err != nil {
-			break
+		var b point.Vec
+		var label rune
+		if _, err := fmt.Sscanf(line, "Button %c: X+%d, Y+%d", &label, &b.Dx, &b.Dy); err == nil {
+			buf.buttons = append(buf.buttons, b)
+			continue
 		}
 
-		if _, err := fmt.Fscanf(r, "Prize: X=%d, Y=%d\n", &buf.x, &buf.y); err != nil {
-			break
+		if _, err := fmt.Sscanf(line, "Prize: X=%d, Y=%d", &buf.prize.X, &buf.prize.Y); err != nil {
+			panic(fmt.Sprintf("invalid line %q", line))
 		}
 
 		input = append(input, buf)
-		if _, err := fmt.Fscanf(r, "\n"); err != nil {
-			break
-		}
+		buf = machine{}
 	}
 
-	return input
+	return
 }
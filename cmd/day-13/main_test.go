@@ -0,0 +1,159 @@
+package main
+
+import (
+	"internal/point"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+const example = `Button A: X+94, Y+34
+Button B: X+22, Y+67
+Prize: X=8400, Y=5400
+
+Button A: X+26, Y+66
+Button B: X+67, Y+21
+Prize: X=12748, Y=12176
+
+Button A: X+17, Y+86
+Button B: X+84, Y+37
+Prize: X=7870, Y=6450
+
+Button A: X+69, Y+23
+Button B: X+27, Y+71
+Prize: X=18641, Y=10279
+`
+
+var COST = []int{3, 1}
+
+// Solve two-button machines with Cramer's rule, which only works when the
+// buttons move the claw in different directions.
+func cramer(m machine) (cost int, soluble bool) {
+	a, b := m.buttons[0], m.buttons[1]
+	det := a.Dx*b.Dy - a.Dy*b.Dx
+	if det == 0 {
+		panic("singular")
+	}
+
+	na := b.Dy*m.prize.X - b.Dx*m.prize.Y
+	nb := a.Dx*m.prize.Y - a.Dy*m.prize.X
+	if na%det != 0 || nb%det != 0 || na/det < 0 || nb/det < 0 {
+		return
+	}
+
+	return COST[0]*(na/det) + COST[1]*(nb/det), true
+}
+
+func TestExample(t *testing.T) {
+	input := readInput(strings.NewReader(example))
+
+	if actual := part1(input, COST); actual != 480 {
+		t.Errorf("part 1: expected 480, got %d", actual)
+	}
+
+	if actual := part2(input, COST); actual != 875318608908 {
+		t.Errorf("part 2: expected 875318608908, got %d", actual)
+	}
+}
+
+func TestCollinearButtons(t *testing.T) {
+	for _, tc := range []struct {
+		m      machine
+		tokens int
+		ok     bool
+	}{
+		// Pressing B three times and A once is cheapest.
+		{machine{[]point.Vec{{Dx: 1, Dy: 1}, {Dx: 3, Dy: 3}}, point.New(10, 10)}, 6, true},
+
+		// A is cheaper per step when B overshoots.
+		{machine{[]point.Vec{{Dx: 2, Dy: 4}, {Dx: 7, Dy: 14}}, point.New(4, 8)}, 6, true},
+
+		// The prize is on the line, but between reachable points.
+		{machine{[]point.Vec{{Dx: 2, Dy: 2}, {Dx: 4, Dy: 4}}, point.New(5, 5)}, 0, false},
+
+		// The prize is off the line.
+		{machine{[]point.Vec{{Dx: 1, Dy: 1}, {Dx: 3, Dy: 3}}, point.New(10, 11)}, 0, false},
+	} {
+		tokens, ok := solve(tc.m, COST)
+		if ok != tc.ok || tokens != tc.tokens {
+			t.Errorf("%v: expected %d (%v), got %d (%v)", tc.m, tc.tokens, tc.ok, tokens, ok)
+		}
+	}
+}
+
+func TestMoreButtons(t *testing.T) {
+	input := readInput(strings.NewReader(`Button A: X+3, Y+1
+Button B: X+1, Y+3
+Button C: X+1, Y+1
+Prize: X=8, Y=8
+`))
+
+	if len(input) != 1 || len(input[0].buttons) != 3 {
+		t.Fatalf("expected one machine with three buttons, got %v", input)
+	}
+
+	// Two presses each of A and B reach the prize, as do eight presses of C, or
+	// a press each of A and B with four of C.
+	for _, tc := range []struct {
+		cost   []int
+		tokens int
+	}{
+		{[]int{3, 1, 5}, 8},
+		{[]int{3, 1, 1}, 8},
+		{[]int{3, 1, 0}, 0},
+		{[]int{1, 1, 2}, 4},
+	} {
+		if tokens, ok := solve(input[0], tc.cost); !ok || tokens != tc.tokens {
+			t.Errorf("cost %v: expected %d, got %d (%v)", tc.cost, tc.tokens, tokens, ok)
+		}
+	}
+}
+
+func TestMoreButtonsFar(t *testing.T) {
+	// Two cheap buttons can make up for each other's moves, so the kernel has
+	// two dimensions, and with the prize moved far away, each of them spans
+	// billions of presses.
+	input := readInput(strings.NewReader(`Button A: X+94, Y+34
+Button B: X+22, Y+67
+Button C: X+1, Y+1
+Button D: X+2, Y+1
+Prize: X=8400, Y=5400
+`))
+
+	cost := []int{3, 1, 1, 1}
+	if actual := part1(input, cost); actual != 280 {
+		t.Errorf("part 1: expected 280, got %d", actual)
+	}
+
+	if actual := part2(input, cost); actual != 351351351641 {
+		t.Errorf("part 2: expected 351351351641, got %d", actual)
+	}
+}
+
+func TestMatchesCramer(t *testing.T) {
+	r := rand.New(rand.NewSource(13))
+	for i := 0; i < 1000; i++ {
+		m := machine{
+			[]point.Vec{
+				{Dx: 1 + r.Intn(99), Dy: 1 + r.Intn(99)},
+				{Dx: 1 + r.Intn(99), Dy: 1 + r.Intn(99)},
+			},
+			point.New(r.Intn(20000), r.Intn(20000)),
+		}
+
+		if m.buttons[0].Dx*m.buttons[1].Dy == m.buttons[0].Dy*m.buttons[1].Dx {
+			continue
+		}
+
+		for _, offset := range []int{0, OFFSET} {
+			m.prize.X += offset
+			m.prize.Y += offset
+
+			expect, eok := cramer(m)
+			actual, aok := solve(m, COST)
+			if expect != actual || eok != aok {
+				t.Fatalf("%v: expected %d (%v), got %d (%v)", m, expect, eok, actual, aok)
+			}
+		}
+	}
+}
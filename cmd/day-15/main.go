@@ -2,49 +2,111 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
 	"internal/grid"
 	"internal/point"
 	"io"
 	"os"
-	"slices"
 )
 
-type cell byte
+type kind byte
+
+// A cell in the warehouse. Every cell that is part of the same box shares the
+// box's ID, and the box moves as one.
+type cell struct {
+	kind  kind
+	box   int
+	glyph byte
+}
 
 const (
-	EMPTY cell = iota
+	EMPTY kind = iota
 	ROBOT
 	BOX
-	BOX_L
-	BOX_R
 	WALL
 )
 
+var EMPTY_CELL = cell{EMPTY, 0, '.'}
+
+var (
+	frame = flag.Int("frame", -1, "render the warehouse after this many moves, instead of solving")
+	wide  = flag.Bool("wide", false, "use the wide warehouse from part 2 when rendering or playing")
+	inter = flag.Bool("play", false, "drive the robot around the warehouse interactively, instead of solving")
+)
+
 func main() {
+	flag.Parse()
+
 	input1, moves := readInput(os.Stdin)
 	input2 := expand(input1)
 
+	warehouse := input1
+	if *wide {
+		warehouse = input2
+	}
+
+	if *inter {
+		if err := play(warehouse, moves); err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	if *frame >= 0 {
+		r := newReplay(warehouse, moves)
+		r.seek(*frame)
+		fmt.Print(r)
+		return
+	}
+
 	fmt.Println("Part 1:", part1(input1, moves))
 	fmt.Println("Part 2:", part2(input2, moves))
 }
 
+// Warehouse maps can include boxes of any shape: 'O' is a box that fills a
+// single cell, "[]" is a box that fills two, and any other letter or digit is
+// part of a box made up of all the cells orthogonally connected to it that
+// share that letter or digit.
 func readInput(r io.Reader) (*grid.Grid[cell], []grid.Dir) {
 	s := bufio.NewScanner(r)
-	g := grid.ScanFunc(s, func(b byte) cell {
-		switch b {
-		case '.':
-			return EMPTY
-		case '@':
-			return ROBOT
-		case '#':
-			return WALL
-		case 'O':
-			return BOX
+	glyphs := grid.ScanFunc(s, func(b byte) byte { return b })
+
+	g := grid.New[cell](glyphs.Width, glyphs.Height)
+	boxes := 0
+	for x, y := range glyphs.Coords() {
+		// Cells that are part of a box may have been filled in already.
+		c := g.Get(x, y)
+		if c.glyph != 0 {
+			continue
+		}
+
+		b := *glyphs.Get(x, y)
+		switch {
+		case b == '.':
+			*c = cell{EMPTY, 0, b}
+		case b == '@':
+			*c = cell{ROBOT, 0, b}
+		case b == '#':
+			*c = cell{WALL, 0, b}
+		case b == 'O':
+			boxes++
+			*c = cell{BOX, boxes, b}
+		case b == '[':
+			if r := glyphs.Get(x+1, y); r == nil || *r != ']' {
+				panic("unmatched '['")
+			}
+
+			boxes++
+			*c = cell{BOX, boxes, b}
+			*g.Get(x+1, y) = cell{BOX, boxes, ']'}
+		case isID(b):
+			boxes++
+			fillBox(glyphs, g, x, y, boxes)
 		default:
-			panic("invalid cell")
+			panic(fmt.Sprintf("invalid cell %q", b))
 		}
-	})
+	}
 
 	var moves []grid.Dir
 	for s.Scan() {
@@ -68,154 +130,170 @@ func readInput(r io.Reader) (*grid.Grid[cell], []grid.Dir) {
 	return g, moves
 }
 
-func part1(g *grid.Grid[cell], moves []grid.Dir) (coords int) {
-	robotX, robotY, found := g.Find(ROBOT)
-	if !found {
-		panic("robot not found")
-	}
+func part1(g *grid.Grid[cell], moves []grid.Dir) int {
+	return simulate(g, moves)
+}
 
-	for _, m := range moves {
-		robotX, robotY = moveLinear(g, m, robotX, robotY)
-	}
+func part2(g *grid.Grid[cell], moves []grid.Dir) int {
+	return simulate(g, moves)
+}
 
-	for x, y := range g.FindAll(BOX) {
-		coords += 100*y + x
+func simulate(g *grid.Grid[cell], moves []grid.Dir) int {
+	robot := findRobot(g)
+	for _, m := range moves {
+		robot.X, robot.Y, _ = step(g, m, robot.X, robot.Y)
 	}
 
-	return
+	return gps(g)
 }
 
-func part2(g *grid.Grid[cell], moves []grid.Dir) (coords int) {
-	robotX, robotY, found := g.Find(ROBOT)
-	if !found {
-		panic("robot not found")
-	}
+// Move the robot at `x, y` one step in direction `d`, if it can. Returns the
+// robot's new position, and the changes made to the warehouse, in the order
+// they were made.
+//
+// Anything that moves pushes whatever is in front of it, so the move cascades
+// from the robot: Moving a cell into part of a box means the whole box moves,
+// which means every cell in front of every cell of that box must move, and so
+// on. The move is blocked if any moving cell would move into a wall.
+func step(g *grid.Grid[cell], d grid.Dir, x, y int) (int, int, []change) {
+	moving := []point.Point{point.New(x, y)}
+	pushed := make(map[int]bool)
+	for i := 0; i < len(moving); i++ {
+		p := moving[i]
+		nextX, nextY := d.Move(p.X, p.Y, 1)
 
-	for _, m := range moves {
-		switch m {
-		case grid.DIR_L, grid.DIR_R:
-			robotX, robotY = moveLinear(g, m, robotX, robotY)
-		case grid.DIR_U, grid.DIR_D:
-			robotX, robotY = moveCascade(g, m, robotX, robotY)
+		next := g.Get(nextX, nextY)
+		if next == nil || next.kind == WALL {
+			return x, y, nil
+		}
+
+		if next.kind == BOX && !pushed[next.box] {
+			pushed[next.box] = true
+			moving = append(moving, boxCells(g, nextX, nextY)...)
 		}
 	}
 
-	for x, y := range g.FindAll(BOX_L) {
-		coords += 100*y + x
+	// Pick everything up before putting it down again, so that nothing is
+	// overwritten before it has moved.
+	var changes []change
+	cells := make([]cell, len(moving))
+	for i, p := range moving {
+		c := g.Get(p.X, p.Y)
+		cells[i] = *c
+		changes = append(changes, change{p, *c, EMPTY_CELL})
+		*c = EMPTY_CELL
 	}
 
-	return
+	for i, p := range moving {
+		nextX, nextY := d.Move(p.X, p.Y, 1)
+		c := g.Get(nextX, nextY)
+		changes = append(changes, change{point.New(nextX, nextY), *c, cells[i]})
+		*c = cells[i]
+	}
+
+	nextX, nextY := d.Move(x, y, 1)
+	return nextX, nextY, changes
 }
 
-func moveLinear(g *grid.Grid[cell], d grid.Dir, x, y int) (int, int) {
-	step := 1
-
-steps:
-	for ; ; step++ {
-		switch *g.Get(d.Move(x, y, step)) {
-		case WALL:
-			return x, y
-		case EMPTY:
-			break steps
-		case BOX, BOX_L, BOX_R:
-			continue
+// All the cells of the box that has a cell at `x, y`.
+func boxCells(g *grid.Grid[cell], x, y int) []point.Point {
+	id := g.Get(x, y).box
+	cells := []point.Point{point.New(x, y)}
+	seen := map[point.Point]bool{cells[0]: true}
+	for i := 0; i < len(cells); i++ {
+		p := cells[i]
+		for _, dir := range []grid.Dir{grid.DIR_U, grid.DIR_R, grid.DIR_D, grid.DIR_L} {
+			nx, ny := dir.Move(p.X, p.Y, 1)
+			q := point.New(nx, ny)
+			if c := g.Get(nx, ny); c != nil && c.kind == BOX && c.box == id && !seen[q] {
+				seen[q] = true
+				cells = append(cells, q)
+			}
 		}
 	}
 
-	write := EMPTY
-	for i := 0; i <= step; i++ {
-		cell := g.Get(d.Move(x, y, i))
-		write, *cell = *cell, write
-	}
-
-	return d.Move(x, y, 1)
+	return cells
 }
 
-func moveCascade(g *grid.Grid[cell], d grid.Dir, x, y int) (int, int) {
-	next := 0
-	var changes []point.Point
-	visited := make(map[point.Point]struct{})
+// Label the box made up of all the cells connected to `x, y` in `glyphs` that
+// share its glyph, with ID `id`.
+func fillBox(glyphs *grid.Grid[byte], g *grid.Grid[cell], x, y, id int) {
+	glyph := *glyphs.Get(x, y)
+	*g.Get(x, y) = cell{BOX, id, glyph}
+
+	frontier := []point.Point{point.New(x, y)}
+	for len(frontier) > 0 {
+		var curr point.Point
+		curr, frontier = frontier[0], frontier[1:]
 
-	var push = func(x, y int) {
-		p := point.New(x, y)
-		if _, ok := visited[p]; !ok {
-			visited[p] = struct{}{}
-			changes = append(changes, p)
+		for _, dir := range []grid.Dir{grid.DIR_U, grid.DIR_R, grid.DIR_D, grid.DIR_L} {
+			nx, ny := dir.Move(curr.X, curr.Y, 1)
+			if b := glyphs.Get(nx, ny); b != nil && *b == glyph && g.Get(nx, ny).glyph == 0 {
+				*g.Get(nx, ny) = cell{BOX, id, glyph}
+				frontier = append(frontier, point.New(nx, ny))
+			}
 		}
 	}
+}
 
-	var pop = func() (int, int) {
-		p := changes[next]
-		next++
-		return p.X, p.Y
+func findRobot(g *grid.Grid[cell]) point.Point {
+	for x, y := range g.Coords() {
+		if g.Get(x, y).kind == ROBOT {
+			return point.New(x, y)
+		}
 	}
 
-	// Gather all the cells that need to be pushed, cascading from the robot's
-	// current position, in direction `d`. The cascade stops if it encounters a
-	// wall, or if all the next positions are empty, meaning the move can happen.
-	push(x, y)
-	for next < len(changes) {
-		currX, currY := pop()
-		pushX, pushY := d.Move(currX, currY, 1)
-
-		switch *g.Get(pushX, pushY) {
-		case WALL:
-			return x, y
-		case EMPTY:
-			break
-		case BOX_L:
-			push(pushX, pushY)
-			push(pushX+1, pushY)
-		case BOX_R:
-			push(pushX, pushY)
-			push(pushX-1, pushY)
+	panic("robot not found")
+}
+
+// The sum of the GPS coordinates of every box, measured from the top and left
+// edges of the box.
+func gps(g *grid.Grid[cell]) (coords int) {
+	corners := make(map[int]point.Point)
+	for x, y := range g.Coords() {
+		c := g.Get(x, y)
+		if c.kind != BOX {
+			continue
+		}
+
+		if p, ok := corners[c.box]; ok {
+			corners[c.box] = point.New(min(p.X, x), min(p.Y, y))
+		} else {
+			corners[c.box] = point.New(x, y)
 		}
 	}
 
-	// Apply the changes backwards to avoid overwriting cells that need to be
-	// referenced later. Leave an empty slot in place of the point.
-	for _, p := range slices.Backward(changes) {
-		cell := g.Get(p.X, p.Y)
-		next := g.Get(d.Move(p.X, p.Y, 1))
-		*next, *cell = *cell, EMPTY
+	for _, p := range corners {
+		coords += 100*p.Y + p.X
 	}
 
-	// Move the robot to its next position
-	return d.Move(x, y, 1)
+	return
 }
 
+// Make the warehouse twice as wide. Boxes keep their IDs, and single-cell
+// boxes become "[]".
 func expand(input *grid.Grid[cell]) *grid.Grid[cell] {
 	output := grid.New[cell](input.Width*2, input.Height)
 
 	for x, y := range input.Coords() {
-		switch *input.Get(x, y) {
-		case ROBOT:
-			*output.Get(x*2, y) = ROBOT
-		case WALL:
-			*output.Get(x*2, y) = WALL
-			*output.Get(x*2+1, y) = WALL
-		case BOX:
-			*output.Get(x*2, y) = BOX_L
-			*output.Get(x*2+1, y) = BOX_R
+		l, r := output.Get(x*2, y), output.Get(x*2+1, y)
+		switch c := *input.Get(x, y); {
+		case c.kind == ROBOT:
+			*l, *r = c, EMPTY_CELL
+		case c.glyph == 'O':
+			*l, *r = cell{BOX, c.box, '['}, cell{BOX, c.box, ']'}
+		default:
+			*l, *r = c, c
 		}
 	}
 
 	return output
 }
 
+func isID(b byte) bool {
+	return 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || '0' <= b && b <= '9'
+}
+
 func (c cell) Format(f fmt.State, _ rune) {
-	switch c {
-	case EMPTY:
-		fmt.Fprint(f, ".")
-	case ROBOT:
-		fmt.Fprint(f, "@")
-	case BOX:
-		fmt.Fprint(f, "O")
-	case WALL:
-		fmt.Fprint(f, "#")
-	case BOX_L:
-		fmt.Fprint(f, "[")
-	case BOX_R:
-		fmt.Fprint(f, "]")
-	}
+	fmt.Fprintf(f, "%c", c.glyph)
 }
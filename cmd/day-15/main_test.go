@@ -0,0 +1,407 @@
+package main
+
+import (
+	"fmt"
+	"internal/grid"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const small = `########
+#..O.O.#
+##@.O..#
+#...O..#
+#.#.O..#
+#...O..#
+#......#
+########
+
+<^^>>>vv<v>>v<<
+`
+
+const large = `##########
+#..O..O.O#
+#......O.#
+#.OO..O.O#
+#..O@..O.#
+#O#..O...#
+#O..O..O.#
+#.OO.O.OO#
+#....O...#
+##########
+
+<vv>^<v^>v>^vv^v>v<>v^v<v<^vv<<<^><<><>>v<vvv<>^v^>^<<<><<v<<<v^vv^v>^
+vvv<<^>^v^^><<>>><>^<<><^vv^^<>vvv<>><^^v>^>vv<>v<<<<v<^v>^<^^>>>^<v<v
+><>vv>v^v^<>><>>>><^^>vv>v<^^^>>v^v^<^^>v^^>v^<^v>v<>>v^v^<v>v^^<^^vv<
+<<v<^>>^^^^>>>v^<>vvv^><v<<<>^^^vv^<vvv>^>v<^^^^v<>^>vvvv><>>v^<<^^^^^
+^><^><>>><>^^<<^^v>>><^<v>^<vv>>v>>>^v><>^v><<<<v>>v<v<v>vvv>^<><<>^><
+^>><>^v<><^vvv<^^<><v<<<<<><^v<<<><<<^^<v<^^^><^>>^<v^><<<^>>^v<v^v<v^
+>^>>^v>vv>^<<^v<>><<><<v<<v><>v<^vv<<<>^^v^>^^>>><<^v>>v^v><^^>>^<>vv^
+<><^^>^^^<><vvvvv^v<v<<>^v<v>v<<^><<><<><<<^^<<<^<<>><<><^^^>^^<>^>v<>
+^^>vv<^v^v<vv>^<><v<^v>^^^>>>^^vvv^>vvv<>>>^<^>>>>>^<<^v>^vvv<>^<><<v>
+v^^>>><<^^<>>^v^<v^vv<>v^<<>^<^v^v><^<<<><<^<v><v<>vv>>v><v^<vv<>v^<<^
+`
+
+// Render a warehouse without the spaces between cells.
+func render(g *grid.Grid[cell]) string {
+	return strings.ReplaceAll(fmt.Sprint(g), " ", "")
+}
+
+// The original implementation, which only supports single cell boxes ('O'),
+// pushed in a line, and two cell boxes ("[]"), pushed in a line horizontally,
+// and in a cascade vertically.
+func reference(rows []string, moves []grid.Dir) (coords int) {
+	g := make([][]byte, len(rows))
+	var x, y int
+	for i, row := range rows {
+		g[i] = []byte(row)
+		if j := strings.IndexByte(row, '@'); j >= 0 {
+			x, y = j, i
+		}
+	}
+
+	for _, d := range moves {
+		dx, dy := d.Move(0, 0, 1)
+		if dy == 0 || !slices.ContainsFunc(rows, func(r string) bool { return strings.Contains(r, "[") }) {
+			n := 1
+			for g[y+n*dy][x+n*dx] == 'O' || g[y+n*dy][x+n*dx] == '[' || g[y+n*dy][x+n*dx] == ']' {
+				n++
+			}
+
+			if g[y+n*dy][x+n*dx] == '#' {
+				continue
+			}
+
+			for i := n; i > 0; i-- {
+				g[y+i*dy][x+i*dx] = g[y+(i-1)*dy][x+(i-1)*dx]
+			}
+
+			g[y][x] = '.'
+			x, y = x+dx, y+dy
+			continue
+		}
+
+		type pos struct{ x, y int }
+		moving := []pos{{x, y}}
+		seen := map[pos]bool{{x, y}: true}
+		blocked := false
+		for i := 0; i < len(moving) && !blocked; i++ {
+			p := pos{moving[i].x, moving[i].y + dy}
+			switch g[p.y][p.x] {
+			case '#':
+				blocked = true
+			case '[', ']':
+				q := pos{p.x + 1, p.y}
+				if g[p.y][p.x] == ']' {
+					q = pos{p.x - 1, p.y}
+				}
+
+				for _, r := range []pos{p, q} {
+					if !seen[r] {
+						seen[r] = true
+						moving = append(moving, r)
+					}
+				}
+			}
+		}
+
+		if blocked {
+			continue
+		}
+
+		for _, p := range slices.Backward(moving) {
+			g[p.y+dy][p.x], g[p.y][p.x] = g[p.y][p.x], '.'
+		}
+
+		y += dy
+	}
+
+	for y, row := range g {
+		for x, b := range row {
+			if b == 'O' || b == '[' {
+				coords += 100*y + x
+			}
+		}
+	}
+
+	return
+}
+
+func replayAll(input string, wide bool) *replay {
+	g, moves := readInput(strings.NewReader(input))
+	if wide {
+		g = expand(g)
+	}
+
+	r := newReplay(g, moves)
+	r.seek(len(moves))
+	return r
+}
+
+func TestExamples(t *testing.T) {
+	for _, tc := range []struct {
+		input        string
+		part1, part2 int
+	}{
+		{small, 2028, 1751},
+		{large, 10092, 9021},
+	} {
+		g, moves := readInput(strings.NewReader(tc.input))
+		if actual := part1(g.Copy(), moves); actual != tc.part1 {
+			t.Errorf("part 1: expected %d, got %d", tc.part1, actual)
+		}
+
+		if actual := part2(expand(g), moves); actual != tc.part2 {
+			t.Errorf("part 2: expected %d, got %d", tc.part2, actual)
+		}
+	}
+}
+
+func TestReplayMatchesParts(t *testing.T) {
+	for _, input := range []string{small, large} {
+		g, moves := readInput(strings.NewReader(input))
+		if expect, actual := part1(g.Copy(), moves), replayAll(input, false).gps(); expect != actual {
+			t.Errorf("narrow: expected %d, got %d", expect, actual)
+		}
+
+		if expect, actual := part2(expand(g), moves), replayAll(input, true).gps(); expect != actual {
+			t.Errorf("wide: expected %d, got %d", expect, actual)
+		}
+	}
+}
+
+func TestReplayBackward(t *testing.T) {
+	for _, wide := range []bool{false, true} {
+		g, moves := readInput(strings.NewReader(large))
+		if wide {
+			g = expand(g)
+		}
+
+		// Remember every frame on the way forward, and check that undoing moves
+		// recovers them.
+		r := newReplay(g, moves)
+		frames := []string{fmt.Sprint(r.warehouse)}
+		for r.forward() {
+			frames = append(frames, fmt.Sprint(r.warehouse))
+		}
+
+		for n := len(frames) - 1; n >= 0; n-- {
+			if actual := fmt.Sprint(r.warehouse); actual != frames[n] {
+				t.Fatalf("wide = %v, frame %d: expected:\n%s\ngot:\n%s", wide, n, frames[n], actual)
+			}
+
+			if p := findRobot(r.warehouse); p != r.robot {
+				t.Fatalf("wide = %v, frame %d: robot at %v, expected %v", wide, n, p, r.robot)
+			}
+
+			r.backward()
+		}
+
+		if r.backward() {
+			t.Errorf("expected nothing left to undo")
+		}
+	}
+}
+
+func TestSeek(t *testing.T) {
+	g, moves := readInput(strings.NewReader(small))
+	r := newReplay(g, moves)
+
+	// The robot's first move is into a wall.
+	r.seek(1)
+	if len(r.diffs[0].changes) != 0 {
+		t.Errorf("expected the first move to be blocked, got %v", r.diffs[0])
+	}
+
+	r.seek(7)
+	expect := fmt.Sprint(r.warehouse)
+
+	r.seek(len(moves))
+	r.seek(7)
+	if actual := fmt.Sprint(r.warehouse); actual != expect {
+		t.Errorf("expected:\n%s\ngot:\n%s", expect, actual)
+	}
+
+	r.seek(100)
+	if r.frame() != len(moves) {
+		t.Errorf("expected to stop at frame %d, got %d", len(moves), r.frame())
+	}
+}
+
+func TestPush(t *testing.T) {
+	g, moves := readInput(strings.NewReader(small))
+	r := newReplay(g, moves)
+	r.seek(3)
+
+	r.push(grid.DIR_D)
+	if r.frame() != 4 || len(r.moves) != 4 || r.moves[3] != grid.DIR_D {
+		t.Errorf("expected moves to be replaced, got %q", formatMoves(r.moves))
+	}
+
+	if r.forward() {
+		t.Errorf("expected no moves after the pushed move")
+	}
+}
+
+func TestFormatMoves(t *testing.T) {
+	r := rand.New(rand.NewSource(15))
+	dirs := []grid.Dir{grid.DIR_U, grid.DIR_R, grid.DIR_D, grid.DIR_L}
+
+	moves := make([]grid.Dir, 2500)
+	for i := range moves {
+		moves[i] = dirs[r.Intn(len(dirs))]
+	}
+
+	_, parsed := readInput(strings.NewReader("#@#\n\n" + formatMoves(moves) + "\n"))
+	if formatMoves(parsed) != formatMoves(moves) {
+		t.Errorf("moves did not survive a round trip")
+	}
+}
+
+func TestShapesVertical(t *testing.T) {
+	g, moves := readInput(strings.NewReader(`#######
+#.....#
+#.B...#
+#AAA..#
+#.A...#
+#.@...#
+#######
+
+^^>^^
+`))
+
+	r := newReplay(g, moves)
+	r.seek(1)
+	expect := "#######\n#.B...#\n#AAA..#\n#.A...#\n#.@...#\n#.....#\n#######\n"
+	if actual := render(r.warehouse); actual != expect {
+		t.Errorf("expected:\n%s\ngot:\n%s", expect, actual)
+	}
+
+	// The box on top is against the wall, so neither box can be pushed any
+	// further, even from under the part of the bottom box that the top box
+	// isn't resting on.
+	r.seek(len(moves))
+	expect = "#######\n#.B...#\n#AAA..#\n#.A@..#\n#.....#\n#.....#\n#######\n"
+	if actual := render(r.warehouse); actual != expect {
+		t.Errorf("expected:\n%s\ngot:\n%s", expect, actual)
+	}
+
+	for _, n := range []int{2, 5} {
+		if d := r.diffs[n-1]; len(d.changes) != 0 {
+			t.Errorf("expected move %d to be blocked, got %v", n, d)
+		}
+	}
+}
+
+func TestShapesHorizontal(t *testing.T) {
+	g, moves := readInput(strings.NewReader(`#########
+#.......#
+#@CCC.D.#
+#.....DD#
+#########
+
+>>v>>>>
+`))
+
+	// The wide box moves once, and then runs into a box that is caught on the
+	// wall by a cell on another row.
+	r := newReplay(g, moves)
+	r.seek(len(moves))
+
+	expect := "#########\n#.......#\n#..CCCD.#\n#....@DD#\n#########\n"
+	if actual := render(r.warehouse); actual != expect {
+		t.Errorf("expected:\n%s\ngot:\n%s", expect, actual)
+	}
+}
+
+func TestBoxIDs(t *testing.T) {
+	g, _ := readInput(strings.NewReader("#######\n#A.A@.#\n#AAO[]#\n#######\n"))
+
+	ids := make(map[int]int)
+	for x, y := range g.Coords() {
+		if c := g.Get(x, y); c.kind == BOX {
+			ids[c.box]++
+		}
+	}
+
+	sizes := make([]int, 0, len(ids))
+	for _, n := range ids {
+		sizes = append(sizes, n)
+	}
+	slices.Sort(sizes)
+
+	// The first 'A' is connected to the 'A's below it, but not the other 'A'.
+	if expect := []int{1, 1, 2, 3}; !slices.Equal(expect, sizes) {
+		t.Errorf("expected box sizes %v, got %v", expect, sizes)
+	}
+
+	// GPS coordinates are measured to the top left of each box.
+	if expect, actual := 101+103+203+204, gps(g); expect != actual {
+		t.Errorf("expected GPS %d, got %d", expect, actual)
+	}
+}
+
+func TestExpandShapes(t *testing.T) {
+	g, moves := readInput(strings.NewReader("########\n#..B...#\n#.AB@..#\n#.A....#\n########\n\n<\n"))
+	w := expand(g)
+
+	expect := "################\n##....BB......##\n##..AABB@.....##\n##..AA........##\n################\n"
+	if actual := render(w); actual != expect {
+		t.Errorf("expected:\n%s\ngot:\n%s", expect, actual)
+	}
+
+	part2(w, moves)
+	expect = "################\n##...BB.......##\n##.AABB@......##\n##.AA.........##\n################\n"
+	if actual := render(w); actual != expect {
+		t.Errorf("expected:\n%s\ngot:\n%s", expect, actual)
+	}
+}
+
+func TestMatchesReference(t *testing.T) {
+	r := rand.New(rand.NewSource(48))
+	dirs := []grid.Dir{grid.DIR_U, grid.DIR_R, grid.DIR_D, grid.DIR_L}
+
+	for i := 0; i < 100; i++ {
+		w, h := 4+r.Intn(8), 4+r.Intn(8)
+		rows := make([]string, h)
+		for y := range rows {
+			var b strings.Builder
+			for x := 0; x < w; x++ {
+				switch {
+				case x == 0 || y == 0 || x == w-1 || y == h-1:
+					b.WriteByte('#')
+				case r.Intn(10) < 3:
+					b.WriteByte('O')
+				case r.Intn(10) < 1:
+					b.WriteByte('#')
+				default:
+					b.WriteByte('.')
+				}
+			}
+			rows[y] = b.String()
+		}
+
+		rx, ry := 1+r.Intn(w-2), 1+r.Intn(h-2)
+		rows[ry] = rows[ry][:rx] + "@" + rows[ry][rx+1:]
+
+		moves := make([]grid.Dir, 200)
+		for j := range moves {
+			moves[j] = dirs[r.Intn(len(dirs))]
+		}
+
+		input := strings.Join(rows, "\n") + "\n\n" + formatMoves(moves) + "\n"
+		g, _ := readInput(strings.NewReader(input))
+		wide := render(expand(g))
+
+		if expect, actual := reference(rows, moves), part1(g.Copy(), moves); expect != actual {
+			t.Fatalf("part 1: expected %d, got %d for:\n%s", expect, actual, input)
+		}
+
+		wideRows := strings.Split(strings.TrimSpace(wide), "\n")
+		if expect, actual := reference(wideRows, moves), part2(expand(g), moves); expect != actual {
+			t.Fatalf("part 2: expected %d, got %d for:\n%s", expect, actual, input)
+		}
+	}
+}
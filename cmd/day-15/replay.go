@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"internal/grid"
+	"internal/point"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// A change to a single cell in the warehouse.
+type change struct {
+	pos           point.Point
+	before, after cell
+}
+
+// The changes a single move made to the warehouse, and where it moved the
+// robot. A move that was blocked makes no changes.
+type diff struct {
+	changes  []change
+	from, to point.Point
+}
+
+// Replays a list of moves on a warehouse, one at a time, remembering the diff
+// of every move made, so that they can be undone.
+type replay struct {
+	warehouse *grid.Grid[cell]
+	robot     point.Point
+	moves     []grid.Dir
+
+	// The diffs of the moves that have been made so far, which is also the
+	// index of the current frame.
+	diffs []diff
+}
+
+var MOVES = map[grid.Dir]byte{
+	grid.DIR_U: '^',
+	grid.DIR_R: '>',
+	grid.DIR_D: 'v',
+	grid.DIR_L: '<',
+}
+
+// The final byte of the escape sequence the terminal sends for each arrow key.
+var ARROWS = map[byte]grid.Dir{
+	'A': grid.DIR_U,
+	'B': grid.DIR_D,
+	'C': grid.DIR_R,
+	'D': grid.DIR_L,
+}
+
+// Set up a replay of `moves` on (a copy of) the warehouse `g`, starting from
+// the frame before any of them are made.
+func newReplay(g *grid.Grid[cell], moves []grid.Dir) *replay {
+	return &replay{g.Copy(), findRobot(g), moves, nil}
+}
+
+// The number of moves made to reach the current frame.
+func (r *replay) frame() int {
+	return len(r.diffs)
+}
+
+// Make the next move, returning false if there are no moves left.
+func (r *replay) forward() bool {
+	if r.frame() >= len(r.moves) {
+		return false
+	}
+
+	x, y, changes := step(r.warehouse, r.moves[r.frame()], r.robot.X, r.robot.Y)
+	to := point.New(x, y)
+
+	r.diffs = append(r.diffs, diff{changes, r.robot, to})
+	r.robot = to
+	return true
+}
+
+// Undo the last move, returning false if there are no moves to undo.
+func (r *replay) backward() bool {
+	if r.frame() == 0 {
+		return false
+	}
+
+	d := r.diffs[len(r.diffs)-1]
+	for i := len(d.changes) - 1; i >= 0; i-- {
+		c := d.changes[i]
+		*r.warehouse.Get(c.pos.X, c.pos.Y) = c.before
+	}
+
+	r.diffs = r.diffs[:len(r.diffs)-1]
+	r.robot = d.from
+	return true
+}
+
+// Step forwards or backwards to frame `n`, or as close to it as possible.
+func (r *replay) seek(n int) {
+	for r.frame() < n && r.forward() {
+	}
+
+	for r.frame() > n && r.backward() {
+	}
+}
+
+// Make move `d` from the current frame, replacing any moves that came after
+// it.
+func (r *replay) push(d grid.Dir) {
+	r.moves = append(r.moves[:r.frame():r.frame()], d)
+	r.forward()
+}
+
+// The sum of the GPS coordinates of every box in the current frame.
+func (r *replay) gps() int {
+	return gps(r.warehouse)
+}
+
+func (r *replay) Format(f fmt.State, _ rune) {
+	fmt.Fprint(f, r.warehouse)
+	if n := r.frame(); n == 0 {
+		fmt.Fprintf(f, "Frame 0/%d: Initial state\n", len(r.moves))
+	} else {
+		d := r.diffs[n-1]
+		status := "moved"
+		if len(d.changes) == 0 {
+			status = "blocked"
+		}
+
+		fmt.Fprintf(f, "Frame %d/%d: Move %c (%s)\n", n, len(r.moves), MOVES[r.moves[n-1]], status)
+	}
+}
+
+// Drive the robot around the warehouse from the terminal, starting from the
+// first frame of replaying `moves` on `g`. Arrow keys move the robot, and
+// replace any moves after the current frame. '[' and ']' step backwards and
+// forwards through the moves, and 'q' quits, printing the moves made up to the
+// current frame, so they can be used as input.
+func play(g *grid.Grid[cell], moves []grid.Dir) error {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer tty.Close()
+
+	// Turn off line buffering and echoing while playing, and restore the
+	// terminal's original settings afterwards.
+	saved, err := stty(tty, "-g")
+	if err != nil {
+		return err
+	}
+	defer stty(tty, strings.TrimSpace(saved))
+
+	if _, err := stty(tty, "cbreak", "-echo"); err != nil {
+		return err
+	}
+
+	r := newReplay(g, moves)
+	keys := bufio.NewReader(tty)
+	for {
+		fmt.Fprint(tty, "\x1b[H\x1b[2J")
+		fmt.Fprint(tty, r)
+		fmt.Fprintf(tty, "GPS: %d\n", r.gps())
+		fmt.Fprintln(tty, "Arrows: move, [/]: step back/forward, q: quit")
+
+		key, err := keys.ReadByte()
+		if err != nil {
+			return err
+		}
+
+		switch key {
+		case 'q':
+			fmt.Println(formatMoves(r.moves[:r.frame()]))
+			return nil
+		case '[':
+			r.backward()
+		case ']':
+			r.forward()
+		case '\x1b':
+			if b, err := keys.ReadByte(); err != nil || b != '[' {
+				continue
+			}
+
+			b, err := keys.ReadByte()
+			if err != nil {
+				return err
+			}
+
+			if d, ok := ARROWS[b]; ok {
+				r.push(d)
+			}
+		}
+	}
+}
+
+func stty(tty *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = tty
+
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// Format moves in the same way as they appear in the puzzle input, wrapping
+// lines every 1000 moves.
+func formatMoves(moves []grid.Dir) string {
+	var b strings.Builder
+	for i, m := range moves {
+		if i > 0 && i%1000 == 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteByte(MOVES[m])
+	}
+
+	return b.String()
+}
@@ -2,8 +2,10 @@ package main
 
 import (
 	"container/heap"
+	"flag"
 	"fmt"
 	"internal/grid"
+	"internal/point"
 	"io"
 	"os"
 )
@@ -37,10 +39,30 @@ const (
 	TURN_COST = 1000
 )
 
+var (
+	k      = flag.Int("routes", 0, "list this many of the cheapest distinct routes through the maze, as moves")
+	render = flag.Bool("render", false, "render the routes listed, or the best seats if no routes are listed, on the maze")
+)
+
 func main() {
+	flag.Parse()
+
 	maze := readInput(os.Stdin)
 	fmt.Println("Part 1:", part1(maze))
 	fmt.Println("Part 2:", part2(maze))
+
+	if *k > 0 {
+		routes := kShortestRoutes(maze, *k)
+		for i, r := range routes {
+			fmt.Printf("Route %d, %v\n", i+1, r)
+		}
+
+		if *render {
+			fmt.Print(renderRoutes(maze, routes))
+		}
+	} else if *render {
+		fmt.Print(renderSeats(maze))
+	}
 }
 
 func readInput(r io.Reader) *grid.Grid[cell] {
@@ -66,7 +88,13 @@ func part1(g *grid.Grid[cell]) (cost int) {
 	return minCost(dists, endX, endY)
 }
 
-func part2(g *grid.Grid[cell]) (seats int) {
+func part2(g *grid.Grid[cell]) int {
+	return len(bestSeats(g))
+}
+
+// Every tile that is part of at least one of the cheapest routes through the
+// maze.
+func bestSeats(g *grid.Grid[cell]) map[point.Point]struct{} {
 	dists := dijkstra(g)
 	endX, endY, _ := g.Find(END)
 	cost := minCost(dists, endX, endY)
@@ -74,6 +102,7 @@ func part2(g *grid.Grid[cell]) (seats int) {
 	// Fill the frontier with all the ending configurations that have minimal cost
 	frontier := make([]config, 0)
 	visited := make(map[config]struct{})
+	seats := make(map[point.Point]struct{})
 	for _, d := range []grid.Dir{grid.DIR_U, grid.DIR_R, grid.DIR_D, grid.DIR_L} {
 		if s, ok := dists[config{endX, endY, d}]; ok && s.dist == cost {
 			frontier = append(frontier, config{endX, endY, d})
@@ -94,7 +123,7 @@ func part2(g *grid.Grid[cell]) (seats int) {
 			visited[curr] = struct{}{}
 		}
 
-		*g.Get(curr.x, curr.y) = SEAT
+		seats[point.New(curr.x, curr.y)] = struct{}{}
 
 		// Check for optimal paths ending in the current configuration that were
 		// preceded by a turn
@@ -121,8 +150,7 @@ func part2(g *grid.Grid[cell]) (seats int) {
 		}
 	}
 
-	fmt.Println(g)
-	return g.Count(SEAT)
+	return seats
 }
 
 func minCost(dists map[config]*state, x, y int) (cost int) {
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"fmt"
+	"internal/grid"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const example1 = `###############
+#.......#....E#
+#.#.###.#.###.#
+#.....#.#...#.#
+#.###.#####.#.#
+#.#.#.......#.#
+#.#.#####.###.#
+#...........#.#
+###.#.#####.#.#
+#...#.....#.#.#
+#.#.#.###.#.#.#
+#.....#...#.#.#
+#.###.#.#.#.#.#
+#S..#.....#...#
+###############
+`
+
+const example2 = `#################
+#...#...#...#..E#
+#.#.#.#.#.#.#.#.#
+#.#.#.#...#...#.#
+#.#.#.#.###.#.#.#
+#...#.#.#.....#.#
+#.#.#.#.#.#####.#
+#.#...#.#.#.....#
+#.#.#####.#.###.#
+#.#.#.......#...#
+#.#.###.#####.###
+#.#.#...#.....#.#
+#.#.#.#####.###.#
+#.#.#.........#.#
+#.#.#.#########.#
+#S#.............#
+#################
+`
+
+// Every route through the maze that never revisits a configuration, and
+// turns canonically, found by exhaustive search.
+func allRoutes(g *grid.Grid[cell]) (routes []route) {
+	startX, startY, _ := g.Find(START)
+	endX, endY, _ := g.Find(END)
+
+	visited := make(map[config]bool)
+	var search func(path []config, t turns)
+	search = func(path []config, t turns) {
+		curr := path[len(path)-1]
+		if curr.x == endX && curr.y == endY {
+			r := route{slices.Clone(path), 0}
+			r.cost = r.total()
+			routes = append(routes, r)
+			return
+		}
+
+		visited[curr] = true
+		for _, next := range successors(g, curr) {
+			if after, ok := t.then(curr, next); ok && !visited[next] {
+				search(append(path, next), after)
+			}
+		}
+		visited[curr] = false
+	}
+
+	search([]config{{startX, startY, grid.DIR_R}}, AFTER_STEP)
+	return
+}
+
+// Follow `moves` through the maze, returning the cost and where it ends up.
+func follow(g *grid.Grid[cell], moves string) (cost int, end config) {
+	x, y, _ := g.Find(START)
+	end = config{x, y, grid.DIR_R}
+	for _, m := range moves {
+		switch m {
+		case 'F':
+			end.x, end.y = end.dir.Move(end.x, end.y, 1)
+			cost += STEP_COST
+		case 'L':
+			end.dir = end.dir.RotateCounterClockwise()
+			cost += TURN_COST
+		case 'R':
+			end.dir = end.dir.RotateClockwise()
+			cost += TURN_COST
+		}
+	}
+
+	return
+}
+
+// A `size` by `size` maze (`size` must be odd), carved out as a random
+// spanning tree of the odd cells, with a fraction `loops` of the remaining
+// inner walls knocked through, so that there are many routes through it. It
+// starts in the bottom-left corner and ends in the top-right, like the puzzle.
+func randomMaze(r *rand.Rand, size int, loops float64) *grid.Grid[cell] {
+	g := grid.New[cell](size, size)
+	for x, y := range g.Coords() {
+		*g.Get(x, y) = WALL
+	}
+
+	var carve func(x, y int)
+	carve = func(x, y int) {
+		*g.Get(x, y) = EMPTY
+		dirs := slices.Clone(DIRS)
+		r.Shuffle(len(dirs), func(i, j int) { dirs[i], dirs[j] = dirs[j], dirs[i] })
+
+		for _, d := range dirs {
+			nextX, nextY := d.Move(x, y, 2)
+			if next := g.Get(nextX, nextY); next != nil && *next == WALL && 0 < nextX && nextX < size-1 && 0 < nextY && nextY < size-1 {
+				wallX, wallY := d.Move(x, y, 1)
+				*g.Get(wallX, wallY) = EMPTY
+				carve(nextX, nextY)
+			}
+		}
+	}
+
+	carve(1, 1)
+	for x := 1; x < size-1; x++ {
+		for y := 1; y < size-1; y++ {
+			if c := g.Get(x, y); *c == WALL && (x+y)%2 == 1 && r.Float64() < loops {
+				*c = EMPTY
+			}
+		}
+	}
+
+	*g.Get(1, size-2) = START
+	*g.Get(size-2, 1) = END
+	return g
+}
+
+func TestExamples(t *testing.T) {
+	for _, tc := range []struct {
+		maze         string
+		part1, part2 int
+	}{
+		{example1, 7036, 45},
+		{example2, 11048, 64},
+	} {
+		g := readInput(strings.NewReader(tc.maze))
+		before := fmt.Sprint(g)
+
+		if actual := part1(g); actual != tc.part1 {
+			t.Errorf("part 1: expected %d, got %d", tc.part1, actual)
+		}
+
+		if actual := part2(g); actual != tc.part2 {
+			t.Errorf("part 2: expected %d, got %d", tc.part2, actual)
+		}
+
+		if after := fmt.Sprint(g); before != after {
+			t.Errorf("expected the maze to be left untouched, got:\n%s", after)
+		}
+	}
+}
+
+func TestRouteMoves(t *testing.T) {
+	for _, maze := range []string{example1, example2} {
+		g := readInput(strings.NewReader(maze))
+		endX, endY, _ := g.Find(END)
+
+		routes := kShortestRoutes(g, 10)
+		if len(routes) != 10 {
+			t.Fatalf("expected 10 routes, got %d", len(routes))
+		}
+
+		seen := make(map[string]bool)
+		for i, r := range routes {
+			moves := r.moves()
+			if seen[moves] {
+				t.Errorf("route %d is a duplicate: %s", i, moves)
+			}
+			seen[moves] = true
+
+			cost, end := follow(g, moves)
+			if cost != r.cost || end.x != endX || end.y != endY {
+				t.Errorf("route %d: expected to reach the end for %d, got %v for %d", i, r.cost, end, cost)
+			}
+
+			if i > 0 && r.cost < routes[i-1].cost {
+				t.Errorf("route %d is cheaper than route %d", i, i-1)
+			}
+		}
+
+		if routes[0].cost != part1(g) {
+			t.Errorf("expected the first route to cost %d, got %d", part1(g), routes[0].cost)
+		}
+	}
+}
+
+func TestBestRoutesCoverSeats(t *testing.T) {
+	g := readInput(strings.NewReader(example1))
+	best := part1(g)
+
+	// The first example has three best routes, which together cover every
+	// best seat.
+	routes := kShortestRoutes(g, 4)
+	seats := make(map[[2]int]bool)
+	for _, r := range routes[:3] {
+		if r.cost != best {
+			t.Fatalf("expected route to cost %d, got %d", best, r.cost)
+		}
+
+		for _, c := range r.configs {
+			seats[[2]int{c.x, c.y}] = true
+		}
+	}
+
+	if routes[3].cost == best {
+		t.Errorf("expected only three best routes")
+	}
+
+	if len(seats) != part2(g) {
+		t.Errorf("expected %d seats, got %d", part2(g), len(seats))
+	}
+}
+
+func TestMatchesExhaustive(t *testing.T) {
+	g := readInput(strings.NewReader(`#######
+#...#E#
+#.#.#.#
+#S....#
+#######
+`))
+
+	all := allRoutes(g)
+	costs := make([]int, len(all))
+	for i, r := range all {
+		costs[i] = r.cost
+	}
+	slices.Sort(costs)
+
+	routes := kShortestRoutes(g, len(all)+5)
+	if len(routes) != len(all) {
+		t.Fatalf("expected %d routes, got %d", len(all), len(routes))
+	}
+
+	for i, r := range routes {
+		if r.cost != costs[i] {
+			t.Errorf("route %d: expected cost %d, got %d", i, costs[i], r.cost)
+		}
+	}
+}
+
+func TestRenderRoutes(t *testing.T) {
+	g := readInput(strings.NewReader("#####\n#S.E#\n#...#\n#####\n"))
+	routes := kShortestRoutes(g, 2)
+
+	expect := "# # # # # \n# S 1 E # \n# . 2 2 # \n# # # # # \n"
+	if actual := fmt.Sprint(renderRoutes(g, routes)); actual != expect {
+		t.Errorf("expected:\n%s\ngot:\n%s", expect, actual)
+	}
+}
+
+func TestRandomMazes(t *testing.T) {
+	r := rand.New(rand.NewSource(16))
+	for i := 0; i < 20; i++ {
+		g := randomMaze(r, 5+2*r.Intn(8), 0.2)
+		routes := kShortestRoutes(g, 5)
+		if len(routes) == 0 || routes[0].cost != part1(g) {
+			t.Fatalf("expected the first route to cost %d, got %v for:\n%v", part1(g), routes, g)
+		}
+
+		for i, r := range routes {
+			if cost, _ := follow(g, r.moves()); cost != r.cost {
+				t.Fatalf("route %d: expected cost %d, got %d", i, r.cost, cost)
+			}
+
+			seen := make(map[config]bool)
+			for _, c := range r.configs {
+				if seen[c] {
+					t.Fatalf("route %d revisits %v", i, c)
+				}
+				seen[c] = true
+			}
+
+			if i > 0 && r.cost < routes[i-1].cost {
+				t.Fatalf("route %d is cheaper than route %d", i, i-1)
+			}
+		}
+	}
+}
+
+func BenchmarkKShortestRoutes(b *testing.B) {
+	// The same size as the puzzle input.
+	g := randomMaze(rand.New(rand.NewSource(141)), 141, 0.05)
+	for _, k := range []int{1, 5, 20} {
+		b.Run(fmt.Sprint(k), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				kShortestRoutes(g, k)
+			}
+		})
+	}
+}
@@ -0,0 +1,423 @@
+package main
+
+import (
+	"cmp"
+	"container/heap"
+	"fmt"
+	"internal/grid"
+	"internal/point"
+	"math/bits"
+	"slices"
+	"strings"
+)
+
+// A route through the maze, as the sequence of configurations the reindeer
+// passes through, from the start, up to its first configuration on the end
+// tile.
+type route struct {
+	configs []config
+	cost    int
+}
+
+// The turns the reindeer has made since its last step. Routes are kept in a
+// canonical form, by only allowing a single turn in either direction, or two
+// turns clockwise (a U-turn), between steps.
+type turns int
+
+// A frontier entry in the search for a shortest route, identified by its
+// index in the dense representation of the configuration space.
+type entry struct {
+	dist, index int
+
+	// For A* searches, the part of `dist` that is estimated. Entries that are
+	// estimated to cost the same are explored in order of how little of that
+	// is estimated, which follows one cheapest route to the end, rather than
+	// exploring all of them at once.
+	estimate int
+}
+
+type entries []entry
+
+// Searches for the cheapest routes to the end of a maze, sharing the work and
+// memory they need between them.
+type router struct {
+	g   *grid.Grid[cell]
+	end point.Point
+
+	// The cheapest cost from each configuration to the end, when nothing is
+	// blocked (see `distancesToEnd`).
+	toEnd []int
+
+	// The state of the latest search, in the dense representation of the
+	// configuration space (see `shortestRoute`).
+	dists, prevs []int
+	stamps       []int32
+	search       int32
+	pq           entries
+}
+
+// A single character drawn in a rendering of the maze.
+type glyph byte
+
+const (
+	AFTER_STEP turns = iota
+	AFTER_LEFT
+	AFTER_RIGHT
+	AFTER_U_TURN
+)
+
+var DIRS = []grid.Dir{grid.DIR_U, grid.DIR_R, grid.DIR_D, grid.DIR_L}
+
+// Glyphs used to draw each route, in order, when rendering routes.
+const ROUTE_GLYPHS = "123456789abcdefghijklmnopqrstuvwxyz"
+
+// The `k` cheapest distinct routes through the maze, cheapest first, using
+// Yen's algorithm over the configuration space: Each route after the first is
+// found by deviating from some prefix of an earlier route (its root), at its
+// last configuration (the spur), while avoiding every way that earlier routes
+// with the same root left the spur, and every configuration on the root
+// (including the spur, once the route has left it), so that routes never
+// revisit a configuration. Routes are found in order of cost, and when several
+// candidates cost the same, the one with the earliest moves is taken first,
+// but a route found later can still cost the same and have earlier moves.
+//
+// Routes that only differ in how they turn on the spot (e.g. turning left
+// three times instead of right once) are not considered distinct: Turns are
+// always made in their canonical form (see `turns`).
+func kShortestRoutes(g *grid.Grid[cell], k int) (routes []route) {
+	if k <= 0 {
+		return
+	}
+
+	startX, startY, foundStart := g.Find(START)
+	endX, endY, foundEnd := g.Find(END)
+	if !foundStart || !foundEnd {
+		panic("maze needs a start and an end")
+	}
+
+	r := newRouter(g, point.New(endX, endY))
+	start := config{startX, startY, grid.DIR_R}
+	first, ok := r.shortestRoute(start, AFTER_STEP, make([]bool, g.Width*g.Height*4), nil)
+	if !ok {
+		return
+	}
+
+	routes = append(routes, first)
+	seen := map[string]bool{first.moves(): true}
+
+	var candidates []route
+	for len(routes) < k {
+		prev := routes[len(routes)-1]
+
+		// The cost of the root, the turns made at its end, the configurations
+		// on it, and the routes that share it, are all kept up to date as the
+		// spur moves along the previous route.
+		cost, turned := 0, AFTER_STEP
+		blocked := make([]bool, g.Width*g.Height*4)
+		sharing := make([]bool, len(routes))
+		for j := range sharing {
+			sharing[j] = true
+		}
+
+		for i := 0; i < len(prev.configs)-1; i++ {
+			spur, root := prev.configs[i], prev.configs[:i+1]
+			if i > 0 {
+				last := prev.configs[i-1]
+				cost += moveCost(last, spur)
+				turned, _ = turned.then(last, spur)
+				blocked[indexOf(g, last, 0)>>2] = true
+			}
+
+			var avoid []config
+			for j, other := range routes {
+				sharing[j] = sharing[j] && len(other.configs) > i && other.configs[i] == spur
+				if sharing[j] && len(other.configs) > i+1 {
+					avoid = append(avoid, other.configs[i+1])
+				}
+			}
+
+			tail, ok := r.shortestRoute(spur, turned, blocked, avoid)
+			if !ok {
+				continue
+			}
+
+			configs := append(slices.Clone(root[:i]), tail.configs...)
+			candidate := route{configs, cost + tail.cost}
+			if moves := candidate.moves(); !seen[moves] {
+				seen[moves] = true
+				candidates = append(candidates, candidate)
+			}
+		}
+
+		if len(candidates) == 0 {
+			break
+		}
+
+		best := 0
+		for i, c := range candidates {
+			if cmp.Or(cmp.Compare(c.cost, candidates[best].cost), strings.Compare(c.moves(), candidates[best].moves())) < 0 {
+				best = i
+			}
+		}
+
+		routes = append(routes, candidates[best])
+		candidates = slices.Delete(candidates, best, best+1)
+	}
+
+	return
+}
+
+func newRouter(g *grid.Grid[cell], end point.Point) *router {
+	n := g.Width * g.Height * 4 * 4
+	return &router{
+		g:      g,
+		end:    end,
+		toEnd:  distancesToEnd(g, end),
+		dists:  make([]int, n),
+		prevs:  make([]int, n),
+		stamps: make([]int32, n),
+	}
+}
+
+// The cheapest cost from every configuration to the `end` tile, indexed by
+// position and direction, or -1 if the end can't be reached from there. Found
+// by searching backwards from the end.
+func distancesToEnd(g *grid.Grid[cell], end point.Point) []int {
+	dists := make([]int, g.Width*g.Height*4)
+	for i := range dists {
+		dists[i] = -1
+	}
+
+	var pq entries
+	for _, d := range DIRS {
+		i := indexOf(g, config{end.X, end.Y, d}, 0) >> 2
+		dists[i] = 0
+		pq = append(pq, entry{0, i, 0})
+	}
+
+	for pq.Len() > 0 {
+		e := heap.Pop(&pq).(entry)
+		if e.dist > dists[e.index] {
+			continue
+		}
+
+		curr, _ := configAt(g, e.index<<2)
+		prevs := []config{
+			{curr.x, curr.y, curr.dir.RotateClockwise()},
+			{curr.x, curr.y, curr.dir.RotateCounterClockwise()},
+		}
+
+		prevX, prevY := curr.dir.Flip().Move(curr.x, curr.y, 1)
+		if c := g.Get(prevX, prevY); c != nil && *c != WALL {
+			prevs = append(prevs, config{prevX, prevY, curr.dir})
+		}
+
+		for _, prev := range prevs {
+			i, d := indexOf(g, prev, 0)>>2, e.dist+moveCost(prev, curr)
+			if dists[i] < 0 || d < dists[i] {
+				dists[i] = d
+				heap.Push(&pq, entry{d, i, 0})
+			}
+		}
+	}
+
+	return dists
+}
+
+// The cheapest route from configuration `src`, reached after `turned`, to any
+// configuration on the end tile, avoiding the configurations marked in
+// `blocked` (indexed by position and direction), without returning to `src`,
+// and without moving straight from `src` to any of the configurations in
+// `avoid`.
+//
+// This is an A* search, guided by the cost to the end when nothing is blocked,
+// which is exact when the cheapest way to the end is not blocked, so the
+// search only strays from it as much as the blocks require. It is repeated
+// many times while finding routes, so it runs over a dense representation of
+// the configuration space (extended with the turns made since the last step),
+// whose buffers are shared between searches.
+func (r *router) shortestRoute(
+	src config,
+	turned turns,
+	blocked []bool,
+	avoid []config,
+) (route, bool) {
+	// Entries in `dists` and `prevs` are only valid if they were stamped by
+	// this search, which avoids clearing them between searches.
+	r.search++
+	r.pq = r.pq[:0]
+
+	visit := func(i, d, prev int) {
+		if h := r.toEnd[i>>2]; h >= 0 && (r.stamps[i] != r.search || d < r.dists[i]) {
+			r.stamps[i], r.dists[i], r.prevs[i] = r.search, d, prev
+			heap.Push(&r.pq, entry{d + h, i, h})
+		}
+	}
+
+	visit(indexOf(r.g, src, turned), 0, -1)
+	for r.pq.Len() > 0 {
+		e := heap.Pop(&r.pq).(entry)
+		dist := r.dists[e.index]
+		if e.dist > dist+r.toEnd[e.index>>2] {
+			continue
+		}
+
+		curr, turned := configAt(r.g, e.index)
+		if curr.x == r.end.X && curr.y == r.end.Y {
+			var configs []config
+			for i := e.index; i >= 0; i = r.prevs[i] {
+				c, _ := configAt(r.g, i)
+				configs = append(configs, c)
+			}
+
+			slices.Reverse(configs)
+			return route{configs, dist}, true
+		}
+
+		for _, next := range successors(r.g, curr) {
+			if next == src || blocked[indexOf(r.g, next, 0)>>2] || (curr == src && slices.Contains(avoid, next)) {
+				continue
+			}
+
+			if after, ok := turned.then(curr, next); ok {
+				visit(indexOf(r.g, next, after), dist+moveCost(curr, next), e.index)
+			}
+		}
+	}
+
+	return route{}, false
+}
+
+// The configurations the reindeer can move to from `c`: stepping forward, if
+// there is no wall in the way, or turning on the spot.
+func successors(g *grid.Grid[cell], c config) []config {
+	next := []config{
+		{c.x, c.y, c.dir.RotateClockwise()},
+		{c.x, c.y, c.dir.RotateCounterClockwise()},
+	}
+
+	stepX, stepY := c.dir.Move(c.x, c.y, 1)
+	if cell := g.Get(stepX, stepY); cell != nil && *cell != WALL {
+		next = append([]config{{stepX, stepY, c.dir}}, next...)
+	}
+
+	return next
+}
+
+func moveCost(from, to config) int {
+	if from.x == to.x && from.y == to.y {
+		return TURN_COST
+	}
+	return STEP_COST
+}
+
+// The turns made after moving from `from` to `to`, having made turns `t`
+// beforehand, and whether that move is allowed.
+func (t turns) then(from, to config) (turns, bool) {
+	switch {
+	case from.x != to.x || from.y != to.y:
+		return AFTER_STEP, true
+	case t == AFTER_STEP && from.dir.RotateCounterClockwise() == to.dir:
+		return AFTER_LEFT, true
+	case t == AFTER_STEP:
+		return AFTER_RIGHT, true
+	case t == AFTER_RIGHT && from.dir.RotateClockwise() == to.dir:
+		return AFTER_U_TURN, true
+	default:
+		return t, false
+	}
+}
+
+func indexOf(g *grid.Grid[cell], c config, t turns) int {
+	return ((c.y*g.Width+c.x)<<2|bits.TrailingZeros(uint(c.dir)))<<2 | int(t)
+}
+
+func configAt(g *grid.Grid[cell], i int) (config, turns) {
+	t, pos := turns(i&3), i>>4
+	return config{pos % g.Width, pos / g.Width, grid.Dir(1 << ((i >> 2) & 3))}, t
+}
+
+// The cost of following the route from its first configuration to its last.
+func (r route) total() (cost int) {
+	for i := 1; i < len(r.configs); i++ {
+		cost += moveCost(r.configs[i-1], r.configs[i])
+	}
+	return
+}
+
+// The route as a sequence of moves: 'F' to step forward, and 'L' or 'R' to
+// turn left (counter-clockwise) or right (clockwise).
+func (r route) moves() string {
+	var b strings.Builder
+	for i := 1; i < len(r.configs); i++ {
+		prev, curr := r.configs[i-1], r.configs[i]
+		switch {
+		case prev.x != curr.x || prev.y != curr.y:
+			b.WriteByte('F')
+		case prev.dir.RotateClockwise() == curr.dir:
+			b.WriteByte('R')
+		default:
+			b.WriteByte('L')
+		}
+	}
+
+	return b.String()
+}
+
+// Draw the routes on the maze, each with its own glyph. Where routes overlap,
+// the cheaper route is drawn on top.
+func renderRoutes(g *grid.Grid[cell], routes []route) *grid.Grid[glyph] {
+	out := grid.New[glyph](g.Width, g.Height)
+	for x, y := range g.Coords() {
+		*out.Get(x, y) = glyph(fmt.Sprint(*g.Get(x, y))[0])
+	}
+
+	for i, r := range slices.Backward(routes) {
+		for _, c := range r.configs {
+			if *g.Get(c.x, c.y) == EMPTY {
+				*out.Get(c.x, c.y) = glyph(ROUTE_GLYPHS[i%len(ROUTE_GLYPHS)])
+			}
+		}
+	}
+
+	return out
+}
+
+// Draw the tiles on any of the cheapest routes on the maze as `SEAT`s.
+func renderSeats(g *grid.Grid[cell]) *grid.Grid[cell] {
+	out := g.Copy()
+	for p := range bestSeats(g) {
+		if c := out.Get(p.X, p.Y); *c == EMPTY {
+			*c = SEAT
+		}
+	}
+
+	return out
+}
+
+func (g glyph) Format(f fmt.State, _ rune) {
+	fmt.Fprintf(f, "%c", byte(g))
+}
+
+func (r route) Format(f fmt.State, _ rune) {
+	fmt.Fprintf(f, "cost %d: %s", r.cost, r.moves())
+}
+
+func (e entries) Len() int { return len(e) }
+func (e entries) Less(i, j int) bool {
+	return cmp.Or(
+		cmp.Compare(e[i].dist, e[j].dist),
+		cmp.Compare(e[i].estimate, e[j].estimate),
+		cmp.Compare(e[i].index, e[j].index),
+	) < 0
+}
+func (e entries) Swap(i, j int) { e[i], e[j] = e[j], e[i] }
+func (e *entries) Push(x any)   { *e = append(*e, x.(entry)) }
+
+func (e *entries) Pop() any {
+	old := *e
+	n := len(old)
+	x := old[n-1]
+	*e = old[:n-1]
+	return x
+}
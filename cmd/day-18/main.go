@@ -1,28 +1,55 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"internal/grid"
 	"internal/point"
+	"internal/unionfind"
 	"io"
-	"math"
 	"os"
+	"slices"
 )
 
-type progress struct {
-	p point.Point
-	d int
+type cell byte
+
+// The outcome of searching for the shortest route from the start to the exit.
+type route struct {
+	// The state of memory that the route was found in.
+	memory *grid.Grid[cell]
+
+	// The cells along the shortest route, from the start to the exit inclusive,
+	// or `nil` if the exit is unreachable.
+	path []point.Point
 }
 
+const (
+	EMPTY cell = iota
+	CORRUPT
+	PATH
+	CUTOFF
+)
+
 const (
 	DIM  = 71
 	DROP = 1024
 )
 
+var render = flag.Bool("render", false, "render memory with the route and fallen bytes overlaid")
+
 func main() {
+	flag.Parse()
+
 	points := readInput(os.Stdin)
 	fmt.Println("Part 1:", part1(points))
+	if *render {
+		fmt.Println(shortestRoute(DIM, points[:DROP]).overlay())
+	}
+
 	fmt.Println("Part 2:", part2(points))
+	if *render {
+		fmt.Println(cutoffOverlay(DIM, points))
+	}
 }
 
 func readInput(r io.Reader) []point.Point {
@@ -42,45 +69,168 @@ func readInput(r io.Reader) []point.Point {
 }
 
 func part1(points []point.Point) int {
-	return floodFillAfter(points[:DROP])
+	steps, ok := shortestRoute(DIM, points[:DROP]).steps()
+	if !ok {
+		panic("exit unreachable")
+	}
+
+	return steps
 }
 
 func part2(points []point.Point) point.Point {
-	for i, p := range points {
-		if floodFillAfter(points[:i+1]) == 0 {
-			return p
+	return points[firstCutoff(DIM, points)]
+}
+
+// Find the index of the first byte in `points` that cuts the exit off from the
+// start of a `dim` by `dim` grid.
+//
+// Rather than re-running the flood fill after every byte, this drops all the
+// bytes at once, and then removes them in reverse order, merging each newly
+// opened cell with its open neighbours in a union-find structure. The first
+// byte whose removal connects the start to the exit is the byte that cut it
+// off.
+func firstCutoff(dim int, points []point.Point) int {
+	// Count the bytes that land on each cell, in case the same cell is hit more
+	// than once -- it only opens up once all of them have been removed.
+	g := grid.New[int](dim, dim)
+	for _, p := range points {
+		*g.Get(p.X, p.Y)++
+	}
+
+	uf := unionfind.New(dim * dim)
+	open := func(x, y int) {
+		for _, d := range []grid.Dir{grid.DIR_U, grid.DIR_R, grid.DIR_D, grid.DIR_L} {
+			nx, ny := d.Move(x, y, 1)
+			if c := g.Get(nx, ny); c != nil && *c == 0 {
+				uf.Union(y*dim+x, ny*dim+nx)
+			}
+		}
+	}
+
+	for x, y := range g.FindAll(0) {
+		open(x, y)
+	}
+
+	start, end := 0, dim*dim-1
+	if uf.Connected(start, end) {
+		panic("no solution")
+	}
+
+	for i := len(points) - 1; i >= 0; i-- {
+		p := points[i]
+		c := g.Get(p.X, p.Y)
+		if *c--; *c > 0 {
+			continue
+		}
+
+		open(p.X, p.Y)
+		if uf.Connected(start, end) {
+			return i
 		}
 	}
 
 	panic("no solution")
 }
 
-func floodFillAfter(points []point.Point) int {
-	g := grid.New[int](DIM, DIM)
+// Render the shortest route through memory just before the first byte that
+// cuts off the exit falls, with that byte marked.
+func cutoffOverlay(dim int, points []point.Point) *grid.Grid[cell] {
+	i := firstCutoff(dim, points)
+	g := shortestRoute(dim, points[:i]).overlay()
+
+	p := points[i]
+	*g.Get(p.X, p.Y) = CUTOFF
+	return g
+}
 
-	// Simulate falling bytes
+// Find the shortest route from the top-left corner of a `dim` by `dim` grid to
+// its bottom-right corner, after `points` have been corrupted.
+func shortestRoute(dim int, points []point.Point) route {
+	memory := grid.New[cell](dim, dim)
 	for _, p := range points {
-		*g.Get(p.X, p.Y) = math.MaxInt
+		*memory.Get(p.X, p.Y) = CORRUPT
 	}
 
-	// Flood fill
-	frontier := []progress{{point.Point{X: 0, Y: 0}, 0}}
+	start, end := point.New(0, 0), point.New(dim-1, dim-1)
+	if *memory.Get(start.X, start.Y) == CORRUPT {
+		return route{memory, nil}
+	}
+
+	// Breadth-first search, remembering the direction each cell was first
+	// entered from, so that the path can be recovered by walking back from the
+	// exit. The start is the only visited cell without a direction.
+	from := grid.New[grid.Dir](dim, dim)
+	frontier := []point.Point{start}
 
-	var curr progress
+	var curr point.Point
 	for len(frontier) > 0 {
 		curr, frontier = frontier[0], frontier[1:]
-		if c := g.Get(curr.p.X, curr.p.Y); c != nil && *c == 0 {
-			*c = curr.d
-		} else {
-			continue
+		if curr == end {
+			break
 		}
 
 		for _, d := range []grid.Dir{grid.DIR_U, grid.DIR_R, grid.DIR_D, grid.DIR_L} {
-			nextX, nextY := d.Move(curr.p.X, curr.p.Y, 1)
-			next := progress{point.Point{X: nextX, Y: nextY}, curr.d + 1}
-			frontier = append(frontier, next)
+			nextX, nextY := d.Move(curr.X, curr.Y, 1)
+			next := point.New(nextX, nextY)
+			if c := memory.Get(nextX, nextY); c == nil || *c == CORRUPT {
+				continue
+			}
+
+			if f := from.Get(nextX, nextY); *f == 0 && next != start {
+				*f = d
+				frontier = append(frontier, next)
+			}
 		}
 	}
 
-	return *g.Get(DIM-1, DIM-1)
+	if *from.Get(end.X, end.Y) == 0 && end != start {
+		return route{memory, nil}
+	}
+
+	path := []point.Point{end}
+	for p := end; p != start; {
+		x, y := from.Get(p.X, p.Y).Flip().Move(p.X, p.Y, 1)
+		p = point.New(x, y)
+		path = append(path, p)
+	}
+
+	slices.Reverse(path)
+	return route{memory, path}
+}
+
+func (r route) reachable() bool {
+	return r.path != nil
+}
+
+// The number of steps taken along the shortest route, and whether there is a
+// route at all.
+func (r route) steps() (int, bool) {
+	if !r.reachable() {
+		return 0, false
+	}
+
+	return len(r.path) - 1, true
+}
+
+// A copy of the memory the route was found in, with the route drawn on it.
+func (r route) overlay() *grid.Grid[cell] {
+	g := r.memory.Copy()
+	for _, p := range r.path {
+		*g.Get(p.X, p.Y) = PATH
+	}
+
+	return g
+}
+
+func (c cell) Format(f fmt.State, _ rune) {
+	switch c {
+	case EMPTY:
+		fmt.Fprint(f, ".")
+	case CORRUPT:
+		fmt.Fprint(f, "#")
+	case PATH:
+		fmt.Fprint(f, "O")
+	case CUTOFF:
+		fmt.Fprint(f, "X")
+	}
 }
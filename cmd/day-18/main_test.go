@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"internal/point"
+	"math/rand"
+	"testing"
+)
+
+// Generate a `dim` by `dim` grid's worth of falling bytes, covering every cell
+// except the start and the exit, in a random order.
+func generateBytes(dim int, seed int64) []point.Point {
+	points := make([]point.Point, 0, dim*dim-2)
+	for y := 0; y < dim; y++ {
+		for x := 0; x < dim; x++ {
+			if (x == 0 && y == 0) || (x == dim-1 && y == dim-1) {
+				continue
+			}
+
+			points = append(points, point.New(x, y))
+		}
+	}
+
+	r := rand.New(rand.NewSource(seed))
+	r.Shuffle(len(points), func(i, j int) {
+		points[i], points[j] = points[j], points[i]
+	})
+
+	return points
+}
+
+// The original quadratic strategy: re-run the flood fill for every prefix of
+// the falling bytes.
+func firstCutoffScan(dim int, points []point.Point) int {
+	for i := range points {
+		if !shortestRoute(dim, points[:i+1]).reachable() {
+			return i
+		}
+	}
+
+	panic("no solution")
+}
+
+func examplePoints() (points []point.Point) {
+	for _, p := range [][2]int{
+		{5, 4}, {4, 2}, {4, 5}, {3, 0}, {2, 1}, {6, 3}, {2, 4}, {1, 5}, {0, 6},
+		{3, 3}, {2, 6}, {5, 1}, {1, 2}, {5, 5}, {2, 5}, {6, 5}, {1, 4}, {0, 4},
+		{6, 4}, {1, 1}, {6, 1}, {1, 0}, {0, 5}, {1, 6}, {2, 0},
+	} {
+		points = append(points, point.New(p[0], p[1]))
+	}
+
+	return
+}
+
+func TestFirstCutoffExample(t *testing.T) {
+	points := examplePoints()
+	if p := points[firstCutoff(7, points)]; p != point.New(6, 1) {
+		t.Errorf("expected 6,1, got %d,%d", p.X, p.Y)
+	}
+}
+
+func TestFirstCutoffMatchesScan(t *testing.T) {
+	for seed := int64(0); seed < 20; seed++ {
+		points := generateBytes(15, seed)
+		if expect, actual := firstCutoffScan(15, points), firstCutoff(15, points); expect != actual {
+			t.Errorf("seed %d: expected %v, got %v", seed, expect, actual)
+		}
+	}
+}
+
+func TestShortestRouteExample(t *testing.T) {
+	r := shortestRoute(7, examplePoints()[:12])
+
+	if steps, ok := r.steps(); !ok || steps != 22 {
+		t.Errorf("expected 22 steps, got %d (reachable: %v)", steps, ok)
+	}
+
+	if r.path[0] != point.New(0, 0) || r.path[len(r.path)-1] != point.New(6, 6) {
+		t.Errorf("expected path from 0,0 to 6,6, got %v", r.path)
+	}
+
+	for i := 1; i < len(r.path); i++ {
+		d := r.path[i].Sub(r.path[i-1])
+		if abs(d.Dx)+abs(d.Dy) != 1 {
+			t.Errorf("non-adjacent steps in path: %v -> %v", r.path[i-1], r.path[i])
+		}
+
+		if *r.memory.Get(r.path[i].X, r.path[i].Y) == CORRUPT {
+			t.Errorf("path passes through corrupted cell %v", r.path[i])
+		}
+	}
+}
+
+func TestShortestRouteUnreachable(t *testing.T) {
+	r := shortestRoute(3, []point.Point{point.New(0, 1), point.New(1, 1), point.New(2, 1)})
+	if _, ok := r.steps(); ok || r.reachable() {
+		t.Errorf("expected exit to be unreachable, got path %v", r.path)
+	}
+}
+
+func TestShortestRouteTrivial(t *testing.T) {
+	r := shortestRoute(1, nil)
+	if steps, ok := r.steps(); !ok || steps != 0 {
+		t.Errorf("expected 0 steps, got %d (reachable: %v)", steps, ok)
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
+
+func BenchmarkFirstCutoff(b *testing.B) {
+	for _, dim := range []int{71, 101} {
+		points := generateBytes(dim, 0)
+
+		b.Run(fmt.Sprintf("scan/%d", dim), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				firstCutoffScan(dim, points)
+			}
+		})
+
+		b.Run(fmt.Sprintf("unionfind/%d", dim), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				firstCutoff(dim, points)
+			}
+		})
+	}
+}
@@ -2,16 +2,36 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
+	"internal/trie"
 	"io"
 	"os"
+	"slices"
 	"strings"
 )
 
+// Why a pattern cannot be made from the available towels.
+type failure struct {
+	// Length of the longest prefix of the pattern that can be made.
+	prefix int
+
+	// Offset of the first stripe that no towel laid after that prefix can match.
+	stuck int
+}
+
+var explain = flag.Int("explain", 0, "list up to this many arrangements of each pattern, and explain the impossible ones")
+
 func main() {
+	flag.Parse()
+
 	towels, patterns := readInput(os.Stdin)
 	fmt.Println("Part 1:", part1(towels, patterns))
 	fmt.Println("Part 2:", part2(towels, patterns))
+
+	if *explain > 0 {
+		explainPatterns(os.Stdout, towels, patterns, *explain)
+	}
 }
 
 func readInput(r io.Reader) (towels []string, patterns []string) {
@@ -32,11 +52,10 @@ func readInput(r io.Reader) (towels []string, patterns []string) {
 }
 
 func part1(towels []string, patterns []string) (possible int) {
-	cache := make(map[string]bool)
-	cache[""] = true
+	matcher := trie.FromWords(towels).Automaton()
 
 	for _, pattern := range patterns {
-		if patternPossible(pattern, towels, cache) {
+		if patternPossible(pattern, matcher) {
 			possible++
 		}
 	}
@@ -44,49 +63,179 @@ func part1(towels []string, patterns []string) (possible int) {
 }
 
 func part2(towels []string, patterns []string) (ways int) {
-	cache := make(map[string]int)
-	cache[""] = 1
+	matcher := trie.FromWords(towels).Automaton()
 
 	for _, pattern := range patterns {
-		ways += patternWays(pattern, towels, cache)
+		ways += patternWays(pattern, matcher)
 	}
 
 	return
 }
 
-func patternPossible(pattern string, towels []string, cache map[string]bool) (possible bool) {
-	if possible, ok := cache[pattern]; ok {
-		return possible
+// Check whether `pattern` can be made by laying towels end-to-end. `matcher`
+// finds every place a towel fits in the pattern, in order of where the towel
+// ends, so by the time a towel ending at `end` is considered, whether the
+// pattern can be made up to its `start` is already known.
+func patternPossible(pattern string, matcher *trie.Automaton) bool {
+	possible := make([]bool, len(pattern)+1)
+	possible[0] = true
+
+	for start, end := range matcher.Matches(pattern) {
+		if possible[start] {
+			possible[end] = true
+		}
 	}
 
-	for _, towel := range towels {
-		if !strings.HasPrefix(pattern, towel) {
+	return possible[len(pattern)]
+}
+
+// Count the ways `pattern` can be made by laying towels end-to-end, by the
+// same strategy as `patternPossible`.
+func patternWays(pattern string, matcher *trie.Automaton) int {
+	ways := make([]int, len(pattern)+1)
+	ways[0] = 1
+
+	for start, end := range matcher.Matches(pattern) {
+		ways[end] += ways[start]
+	}
+
+	return ways[len(pattern)]
+}
+
+// Describe how each of `patterns` can be made from `towels`: listing at most
+// `limit` of its arrangements, and the arrangement that uses the fewest towels,
+// or if it cannot be made, how far it gets before every towel fails.
+func explainPatterns(w io.Writer, towels []string, patterns []string, limit int) {
+	rack := trie.FromWords(towels)
+	matcher := rack.Automaton()
+
+	for _, pattern := range patterns {
+		if !patternPossible(pattern, matcher) {
+			f := diagnose(pattern, rack)
+			fmt.Fprintf(w, "%s: impossible, longest prefix %q, every towel fails at %d\n",
+				pattern, pattern[:f.prefix], f.stuck)
 			continue
 		}
 
-		if patternPossible(pattern[len(towel):], towels, cache) {
-			possible = true
-			break
+		fewest := fewestTowels(pattern, rack)
+		fmt.Fprintf(w, "%s: %d ways, fewest towels %d: %s\n",
+			pattern, patternWays(pattern, matcher), len(fewest), strings.Join(fewest, ","))
+
+		listed := 0
+		for arrangement := range arrangements(pattern, rack) {
+			fmt.Fprintf(w, "  %s\n", strings.Join(arrangement, ","))
+			if listed++; listed >= limit {
+				break
+			}
 		}
 	}
+}
 
-	cache[pattern] = possible
-	return
+// Returns the arrangements of towels in `rack` that make `pattern`, preferring
+// shorter towels earlier in the pattern. Arrangements are produced lazily, and
+// only partial arrangements that can be completed are explored, so each one
+// is found in time proportional to its length.
+func arrangements(pattern string, rack *trie.Trie) func(yield func([]string) bool) {
+	return func(yield func([]string) bool) {
+		finishes := finishable(pattern, rack)
+		if !finishes[0] {
+			return
+		}
+
+		var towels []string
+		var search func(start int) bool
+		search = func(start int) bool {
+			if start == len(pattern) {
+				return yield(slices.Clone(towels))
+			}
+
+			for l := range rack.Prefixes(pattern[start:]) {
+				if l == 0 || !finishes[start+l] {
+					continue
+				}
+
+				towels = append(towels, pattern[start:start+l])
+				if !search(start + l) {
+					return false
+				}
+				towels = towels[:len(towels)-1]
+			}
+
+			return true
+		}
+
+		search(0)
+	}
 }
 
-func patternWays(pattern string, towels []string, cache map[string]int) (ways int) {
-	if ways, ok := cache[pattern]; ok {
-		return ways
+// Find the arrangement of towels in `rack` that makes `pattern` using the
+// fewest towels. Returns `nil` if the pattern cannot be made.
+func fewestTowels(pattern string, rack *trie.Trie) (towels []string) {
+	// fewest[i] is the fewest towels needed to make `pattern[i:]`, and next[i]
+	// is the length of the first towel in that arrangement.
+	fewest := make([]int, len(pattern)+1)
+	next := make([]int, len(pattern)+1)
+	for i := len(pattern) - 1; i >= 0; i-- {
+		for l := range rack.Prefixes(pattern[i:]) {
+			if l == 0 || (i+l < len(pattern) && fewest[i+l] == 0) {
+				continue
+			}
+
+			if fewest[i] == 0 || fewest[i+l]+1 < fewest[i] {
+				fewest[i], next[i] = fewest[i+l]+1, l
+			}
+		}
 	}
 
-	for _, towel := range towels {
-		if !strings.HasPrefix(pattern, towel) {
+	if len(pattern) > 0 && fewest[0] == 0 {
+		return nil
+	}
+
+	for i := 0; i < len(pattern); i += next[i] {
+		towels = append(towels, pattern[i:i+next[i]])
+	}
+
+	return
+}
+
+// Explain why `pattern` cannot be made from the towels in `rack`.
+func diagnose(pattern string, rack *trie.Trie) (f failure) {
+	reachable := make([]bool, len(pattern)+1)
+	reachable[0] = true
+
+	for i := range pattern {
+		if !reachable[i] {
 			continue
 		}
 
-		ways += patternWays(pattern[len(towel):], towels, cache)
+		f.prefix = i
+		for l := range rack.Prefixes(pattern[i:]) {
+			reachable[i+l] = true
+		}
+	}
+
+	if reachable[len(pattern)] {
+		f.prefix = len(pattern)
 	}
 
-	cache[pattern] = ways
+	f.stuck = f.prefix + rack.LongestPrefix(pattern[f.prefix:])
 	return
 }
+
+// For each offset `i` into `pattern`, whether `pattern[i:]` can be made from
+// the towels in `rack`.
+func finishable(pattern string, rack *trie.Trie) []bool {
+	finishes := make([]bool, len(pattern)+1)
+	finishes[len(pattern)] = true
+
+	for i := len(pattern) - 1; i >= 0; i-- {
+		for l := range rack.Prefixes(pattern[i:]) {
+			if l > 0 && finishes[i+l] {
+				finishes[i] = true
+				break
+			}
+		}
+	}
+
+	return finishes
+}
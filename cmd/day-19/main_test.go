@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"internal/trie"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var (
+	exampleTowels   = []string{"r", "wr", "b", "g", "bwu", "rb", "gb", "br"}
+	examplePatterns = []string{
+		"brwrr", "bggr", "gbbr", "rrbgbr", "ubwu", "bwurrg", "brgr", "bbrwb",
+	}
+)
+
+// The original strategy: try every towel at the start of the pattern, and
+// memoise on the remaining suffix.
+func patternWaysMemo(pattern string, towels []string, cache map[string]int) (ways int) {
+	if ways, ok := cache[pattern]; ok {
+		return ways
+	}
+
+	for _, towel := range towels {
+		if !strings.HasPrefix(pattern, towel) {
+			continue
+		}
+
+		ways += patternWaysMemo(pattern[len(towel):], towels, cache)
+	}
+
+	cache[pattern] = ways
+	return
+}
+
+// Generate `n` distinct towels, with stripes chosen at random, of lengths
+// between 1 and `maxLen`.
+func generateTowels(r *rand.Rand, n, maxLen int) []string {
+	seen := make(map[string]bool)
+	towels := make([]string, 0, n)
+	for len(towels) < n {
+		towel := generateStripes(r, 1+r.Intn(maxLen))
+		if !seen[towel] {
+			seen[towel] = true
+			towels = append(towels, towel)
+		}
+	}
+	return towels
+}
+
+func generateStripes(r *rand.Rand, n int) string {
+	const colors = "wubrg"
+
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		b.WriteByte(colors[r.Intn(len(colors))])
+	}
+	return b.String()
+}
+
+func TestExample(t *testing.T) {
+	if actual := part1(exampleTowels, examplePatterns); actual != 6 {
+		t.Errorf("part 1: expected 6, got %d", actual)
+	}
+
+	if actual := part2(exampleTowels, examplePatterns); actual != 16 {
+		t.Errorf("part 2: expected 16, got %d", actual)
+	}
+}
+
+func TestMatchesMemo(t *testing.T) {
+	r := rand.New(rand.NewSource(0))
+	for i := 0; i < 20; i++ {
+		towels := generateTowels(r, 50, 6)
+		matcher := trie.FromWords(towels).Automaton()
+		cache := map[string]int{"": 1}
+
+		for j := 0; j < 20; j++ {
+			pattern := generateStripes(r, 20+r.Intn(20))
+			expect := patternWaysMemo(pattern, towels, cache)
+			if actual := patternWays(pattern, matcher); expect != actual {
+				t.Errorf("%q: expected %d ways, got %d", pattern, expect, actual)
+			}
+
+			if actual := patternPossible(pattern, matcher); (expect > 0) != actual {
+				t.Errorf("%q: expected possible = %v, got %v", pattern, expect > 0, actual)
+			}
+		}
+	}
+}
+
+func BenchmarkPatternWays(b *testing.B) {
+	for _, n := range []int{500, 5000} {
+		r := rand.New(rand.NewSource(0))
+		towels := generateTowels(r, n, 8)
+		patterns := make([]string, 400)
+		for i := range patterns {
+			patterns[i] = generateStripes(r, 60)
+		}
+
+		b.Run(fmt.Sprintf("memo/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				cache := map[string]int{"": 1}
+				for _, pattern := range patterns {
+					patternWaysMemo(pattern, towels, cache)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("automaton/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				matcher := trie.FromWords(towels).Automaton()
+				for _, pattern := range patterns {
+					patternWays(pattern, matcher)
+				}
+			}
+		})
+	}
+}
+
+func TestArrangements(t *testing.T) {
+	rack := trie.FromWords(exampleTowels)
+
+	var actual []string
+	for arrangement := range arrangements("rrbgbr", rack) {
+		actual = append(actual, strings.Join(arrangement, ","))
+	}
+
+	expect := []string{
+		"r,r,b,g,b,r",
+		"r,r,b,g,br",
+		"r,r,b,gb,r",
+		"r,rb,g,b,r",
+		"r,rb,g,br",
+		"r,rb,gb,r",
+	}
+
+	if !slices.Equal(expect, actual) {
+		t.Errorf("expected %v, got %v", expect, actual)
+	}
+}
+
+func TestArrangementsLimit(t *testing.T) {
+	rack := trie.FromWords([]string{"a", "aa"})
+
+	count := 0
+	for range arrangements(strings.Repeat("a", 80), rack) {
+		if count++; count == 5 {
+			break
+		}
+	}
+
+	if count != 5 {
+		t.Errorf("expected to stop after 5 arrangements, got %d", count)
+	}
+}
+
+func TestFewestTowels(t *testing.T) {
+	rack := trie.FromWords(exampleTowels)
+
+	for pattern, expect := range map[string][]string{
+		"brwrr":  {"br", "wr", "r"},
+		"bggr":   {"b", "g", "g", "r"},
+		"rrbgbr": {"r", "rb", "g", "br"},
+		"bwurrg": {"bwu", "r", "r", "g"},
+		"ubwu":   nil,
+		"bbrwb":  nil,
+	} {
+		if actual := fewestTowels(pattern, rack); !slices.Equal(expect, actual) {
+			t.Errorf("%s: expected %v, got %v", pattern, expect, actual)
+		}
+	}
+}
+
+func TestDiagnose(t *testing.T) {
+	rack := trie.FromWords(exampleTowels)
+
+	for pattern, expect := range map[string]failure{
+		"ubwu":  {0, 0},
+		"bbrwb": {3, 4},
+		"bwx":   {1, 2},
+	} {
+		if actual := diagnose(pattern, rack); expect != actual {
+			t.Errorf("%s: expected %+v, got %+v", pattern, expect, actual)
+		}
+	}
+}
@@ -1,28 +1,64 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"internal/grid"
+	"internal/point"
 	"io"
-	"math"
+	"maps"
 	"os"
+	"slices"
 )
 
-type cell int
+type cell byte
+
+// A shortcut through walls, from `from` to `to`, and the time it saves
+// compared to the fastest honest race.
+type cheat struct {
+	from, to point.Point
+	saving   int
+}
+
+// Distances to every cell on the track from the start, and to the end, as
+// well as the time taken by the fastest honest race. Unreachable cells have
+// distance -1.
+type race struct {
+	fromStart *grid.Grid[int]
+	toEnd     *grid.Grid[int]
+	best      int
+}
 
 const (
-	EMPTY cell = math.MinInt
-	START      = math.MinInt + 1
-	END        = math.MinInt + 2
-	WALL       = math.MinInt + 3
+	EMPTY cell = iota
+	START
+	END
+	WALL
+)
+
+var (
+	list      = flag.Bool("list", false, "list every cheat that saves at least -saving picoseconds")
+	histogram = flag.Bool("histogram", false, "count cheats that save at least -saving picoseconds, by saving")
+	saving    = flag.Int("saving", 100, "minimum picoseconds a cheat must save to be counted")
 )
 
 func main() {
-	g := readInput(os.Stdin)
+	flag.Parse()
 
-	floodFill(g)
-	fmt.Println("Part 1:", countShortcuts(g, 2, 100))
-	fmt.Println("Part 2:", countShortcuts(g, 20, 100))
+	r := newRace(readInput(os.Stdin))
+	for part, dist := range []int{2, 20} {
+		fmt.Printf("Part %d: %d\n", part+1, countShortcuts(r, dist, *saving))
+
+		if *list {
+			for c := range r.cheats(dist, *saving) {
+				fmt.Printf("  %d,%d -> %d,%d saves %d\n", c.from.X, c.from.Y, c.to.X, c.to.Y, c.saving)
+			}
+		}
+
+		if *histogram {
+			printHistogram(os.Stdout, r, dist, *saving)
+		}
+	}
 }
 
 func readInput(r io.Reader) *grid.Grid[cell] {
@@ -42,49 +78,115 @@ func readInput(r io.Reader) *grid.Grid[cell] {
 	})
 }
 
-func floodFill(g *grid.Grid[cell]) {
-	x, y, ok := g.Find(START)
+func newRace(g *grid.Grid[cell]) (r race) {
+	startX, startY, ok := g.Find(START)
 	if !ok {
 		panic("no start position")
 	}
 
-outer:
-	for curr := 0; ; curr++ {
-		*g.Get(x, y) = cell(curr)
+	endX, endY, ok := g.Find(END)
+	if !ok {
+		panic("no end position")
+	}
+
+	r.fromStart = floodFill(g, startX, startY)
+	r.toEnd = floodFill(g, endX, endY)
+
+	if r.best = *r.fromStart.Get(endX, endY); r.best < 0 {
+		panic("end unreachable")
+	}
+
+	return
+}
 
-		for _, d := range []grid.Dir{grid.DIR_U, grid.DIR_R, grid.DIR_D, grid.DIR_L} {
-			nx, ny := d.Move(x, y, 1)
-			if c := g.Get(nx, ny); c != nil && (*c == EMPTY || *c == END) {
-				x, y = nx, ny
-				continue outer
+// Breadth-first search from `(x, y)` to every cell in the track, returning the
+// distance to each cell, or -1 for cells that cannot be reached.
+func floodFill(g *grid.Grid[cell], x, y int) *grid.Grid[int] {
+	dists := grid.New[int](g.Width, g.Height)
+	for x, y := range dists.Coords() {
+		*dists.Get(x, y) = -1
+	}
+
+	*dists.Get(x, y) = 0
+	frontier := []point.Point{point.New(x, y)}
+
+	var curr point.Point
+	for len(frontier) > 0 {
+		curr, frontier = frontier[0], frontier[1:]
+		d := *dists.Get(curr.X, curr.Y)
+
+		for _, dir := range []grid.Dir{grid.DIR_U, grid.DIR_R, grid.DIR_D, grid.DIR_L} {
+			nx, ny := dir.Move(curr.X, curr.Y, 1)
+			if c := g.Get(nx, ny); c == nil || *c == WALL {
+				continue
+			}
+
+			if n := dists.Get(nx, ny); *n < 0 {
+				*n = d + 1
+				frontier = append(frontier, point.New(nx, ny))
 			}
 		}
+	}
+
+	return dists
+}
 
-		break
+func countShortcuts(r race, dist, saving int) (shortcuts int) {
+	for range r.cheats(dist, saving) {
+		shortcuts++
 	}
+
+	return
 }
 
-func countShortcuts(g *grid.Grid[cell], dist, saving int) (shortcuts int) {
-	for x, y := range g.Coords() {
-		from := g.Get(x, y)
-		if *from == WALL {
-			continue
-		}
+// Returns every cheat lasting at most `dist` picoseconds that saves at least
+// `saving` picoseconds. A cheat from `a` to `b` results in a race that takes as
+// long as it takes to get to `a` from the start, plus the time spent cheating,
+// plus the time it takes to get from `b` to the end.
+func (r race) cheats(dist, saving int) func(yield func(cheat) bool) {
+	return func(yield func(cheat) bool) {
+		for x, y := range r.fromStart.Coords() {
+			before := *r.fromStart.Get(x, y)
+			if before < 0 {
+				continue
+			}
 
-		for i := x - dist; i <= x+dist; i++ {
-			vdist := dist - abs(i-x)
-			for j := y - vdist; j <= y+vdist; j++ {
-				travel := abs(x-i) + abs(y-j)
-				if to := g.Get(i, j); to != nil && *to != WALL {
-					if int(*to)-int(*from)-travel >= saving {
-						shortcuts++
+			for i := x - dist; i <= x+dist; i++ {
+				vdist := dist - abs(i-x)
+				for j := y - vdist; j <= y+vdist; j++ {
+					to := r.toEnd.Get(i, j)
+					if to == nil || *to < 0 {
+						continue
+					}
+
+					travel := abs(x-i) + abs(y-j)
+					if s := r.best - (before + travel + *to); s >= saving {
+						if !yield(cheat{point.New(x, y), point.New(i, j), s}) {
+							return
+						}
 					}
 				}
 			}
 		}
 	}
+}
+
+// Summarise the cheats lasting at most `dist` picoseconds that save at least
+// `saving` picoseconds, by how much time they save, in the style of the
+// puzzle's description.
+func printHistogram(w io.Writer, r race, dist, saving int) {
+	counts := make(map[int]int)
+	for c := range r.cheats(dist, saving) {
+		counts[c.saving]++
+	}
 
-	return
+	for _, s := range slices.Sorted(maps.Keys(counts)) {
+		if n := counts[s]; n == 1 {
+			fmt.Fprintf(w, "  There is one cheat that saves %d picoseconds.\n", s)
+		} else {
+			fmt.Fprintf(w, "  There are %d cheats that save %d picoseconds.\n", n, s)
+		}
+	}
 }
 
 func abs(n int) int {
@@ -95,17 +197,15 @@ func abs(n int) int {
 	}
 }
 
-func (c cell) Format(f fmt.State, r rune) {
+func (c cell) Format(f fmt.State, _ rune) {
 	switch c {
 	case EMPTY:
-		fmt.Fprint(f, "....")
+		fmt.Fprint(f, ".")
 	case START:
-		fmt.Fprint(f, "SSSS")
+		fmt.Fprint(f, "S")
 	case END:
-		fmt.Fprint(f, "EEEE")
+		fmt.Fprint(f, "E")
 	case WALL:
-		fmt.Fprint(f, "####")
-	default:
-		fmt.Fprintf(f, "%04x", int(c))
+		fmt.Fprint(f, "#")
 	}
 }
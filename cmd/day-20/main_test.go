@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const example = `###############
+#...#...#.....#
+#.#.#.#.#.###.#
+#S#...#.#.#...#
+#######.#.#.###
+#######.#.#...#
+#######.#.###.#
+###..E#...#...#
+###.#######.###
+#...###...#...#
+#.#####.#.###.#
+#.#...#.#.#...#
+#.#.#.#.#.#.###
+#...#...#...###
+###############
+`
+
+func TestExampleHistogram(t *testing.T) {
+	r := newRace(readInput(strings.NewReader(example)))
+
+	var out strings.Builder
+	printHistogram(&out, r, 2, 1)
+
+	expect := `  There are 14 cheats that save 2 picoseconds.
+  There are 14 cheats that save 4 picoseconds.
+  There are 2 cheats that save 6 picoseconds.
+  There are 4 cheats that save 8 picoseconds.
+  There are 2 cheats that save 10 picoseconds.
+  There are 3 cheats that save 12 picoseconds.
+  There is one cheat that saves 20 picoseconds.
+  There is one cheat that saves 36 picoseconds.
+  There is one cheat that saves 38 picoseconds.
+  There is one cheat that saves 40 picoseconds.
+  There is one cheat that saves 64 picoseconds.
+`
+
+	if actual := out.String(); actual != expect {
+		t.Errorf("expected:\n%s\ngot:\n%s", expect, actual)
+	}
+}
+
+func TestExampleLongCheats(t *testing.T) {
+	r := newRace(readInput(strings.NewReader(example)))
+	if actual := countShortcuts(r, 20, 50); actual != 285 {
+		t.Errorf("expected 285 cheats saving at least 50, got %d", actual)
+	}
+}
+
+func TestBranchingTrack(t *testing.T) {
+	// The track forks three ways at the start: two equally long loops around to
+	// the end, and a dead end that stops one wall short of it. The only cheat
+	// worth taking breaks through that wall.
+	const track = `#########
+#.......#
+#.#####.#
+#S....#E#
+#.#####.#
+#.......#
+#########
+`
+
+	r := newRace(readInput(strings.NewReader(track)))
+	if r.best != 10 {
+		t.Errorf("expected best race of 10, got %d", r.best)
+	}
+
+	var cheats []cheat
+	for c := range r.cheats(2, 1) {
+		cheats = append(cheats, c)
+	}
+
+	if len(cheats) != 1 || cheats[0].saving != 4 {
+		t.Errorf("expected one cheat saving 4, got %v", cheats)
+	}
+}
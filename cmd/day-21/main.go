@@ -2,196 +2,421 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
 	"internal/point"
 	"io"
 	"math"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 )
 
-type cacheKey struct {
-	move point.Vec
-	bias bias
+type keyPad map[rune]point.Point
+
+// A key pad in a chain of robots, with the cheapest way to press each of its
+// keys, starting from each other key.
+type layer struct {
+	pad keyPad
+
+	// The layer whose key presses direct the arm pointing at this layer's key
+	// pad, or `nil` if this key pad is pressed directly by a human.
+	ctrl *layer
+
+	moves map[move]plan
 }
 
-type cache map[cacheKey]int
+type move struct {
+	from, to rune
+}
 
-// Whether we need to bias the first direction we move in to avoid the X slot.
-type bias byte
+// How to press a key on a layer's key pad.
+type plan struct {
+	// The number of keys the human needs to press to make this move.
+	cost int
 
-type keyPad map[rune]point.Point
+	// The keys to press on the controlling layer's key pad to make this move,
+	// always ending in 'A'. Empty for key pads pressed by a human.
+	presses string
+}
+
+// A chain of robots, replaying the keys a human presses. The first robot
+// presses keys on the key pad that codes are typed on, and every other robot
+// presses keys on the directional key pad that directs the robot before it.
+// The human directs the last robot.
+type simulator struct {
+	pads []keyPad
+	arms []point.Point
+}
+
+// A robot panicking because its arm was directed over a gap, or off the edge
+// of its key pad.
+type panicError struct {
+	// The robot that panicked, counting from the one typing the code.
+	robot int
+
+	// The index of the human's key press that caused the panic.
+	press int
+
+	// Where the robot's arm ended up.
+	pos point.Point
+}
+
+// A position in the search for the cheapest plan: where the arm is pointing on
+// the key pad being planned for, and where the arm is pointing on the key pad
+// controlling it.
+type config struct {
+	pos  point.Point
+	ctrl rune
+}
 
 const (
-	VERT bias = 0b01
-	HORZ bias = 0b10
-	NONE bias = 0b11
+	// The number of directional key pads in the chain for each part, including
+	// the one the human presses.
+	PART1_DEPTH = 3
+	PART2_DEPTH = 26
 )
 
-var NUMERIC_KEYS = keyPad{
-	'7': {X: 0, Y: 0},
-	'8': {X: 1, Y: 0},
-	'9': {X: 2, Y: 0},
-	'4': {X: 0, Y: 1},
-	'5': {X: 1, Y: 1},
-	'6': {X: 2, Y: 1},
-	'1': {X: 0, Y: 2},
-	'2': {X: 1, Y: 2},
-	'3': {X: 2, Y: 2},
-	'X': {X: 0, Y: 3},
-	'0': {X: 1, Y: 3},
-	'A': {X: 2, Y: 3},
-}
-
-var DIRECTION_KEYS = keyPad{
-	'X': {X: 0, Y: 0},
-	'^': {X: 1, Y: 0},
-	'A': {X: 2, Y: 0},
-	'<': {X: 0, Y: 1},
-	'v': {X: 1, Y: 1},
-	'>': {X: 2, Y: 1},
+// Each non-space character in a layout is a key, and spaces are gaps that a
+// robot's arm must never point at.
+var NUMERIC_KEYS = parseKeyPad(`
+789
+456
+123
+ 0A
+`)
+
+var DIRECTION_KEYS = parseKeyPad(`
+ ^A
+<v>
+`)
+
+var DIRECTIONS = map[rune]point.Vec{
+	'^': {Dx: 0, Dy: -1},
+	'>': {Dx: 1, Dy: 0},
+	'v': {Dx: 0, Dy: 1},
+	'<': {Dx: -1, Dy: 0},
 }
 
+var (
+	emit   = flag.Bool("emit", false, "print the shortest sequence of key presses at the human's key pad for each code")
+	replay = flag.Bool("replay", false, "read sequences of key presses at the human's key pad, and print the codes they type")
+	depth  = flag.Int("depth", PART1_DEPTH, "number of directional key pads in the chain when emitting or replaying key presses")
+)
+
 func main() {
+	flag.Parse()
+
+	if *replay {
+		for _, presses := range readInput(os.Stdin) {
+			typed, err := newSimulator(NUMERIC_KEYS, DIRECTION_KEYS, *depth).replay(presses)
+			if err != nil {
+				fmt.Printf("%s: %s (typed %q)\n", presses, err, typed)
+			} else {
+				fmt.Printf("%s: %s\n", presses, typed)
+			}
+		}
+		return
+	}
+
 	codes := readInput(os.Stdin)
 	fmt.Println("Part 1:", part1(codes))
 	fmt.Println("Part 2:", part2(codes))
+
+	if *emit {
+		chain := newChain(NUMERIC_KEYS, DIRECTION_KEYS, *depth)
+		for _, code := range codes {
+			presses := chain.sequence(code)
+			sim := newSimulator(NUMERIC_KEYS, DIRECTION_KEYS, *depth)
+			if typed, err := sim.replay(presses); err != nil {
+				panic(err)
+			} else if typed != code {
+				panic(fmt.Sprintf("sequence for %s types %s", code, typed))
+			}
+
+			fmt.Printf("%s: %s\n", code, presses)
+		}
+	}
+}
+
+func part1(codes []string) int {
+	return complexity(codes, newChain(NUMERIC_KEYS, DIRECTION_KEYS, PART1_DEPTH))
+}
+
+func part2(codes []string) int {
+	return complexity(codes, newChain(NUMERIC_KEYS, DIRECTION_KEYS, PART2_DEPTH))
 }
 
-func part1(codes []string) (total int) {
-	var caches []cache
-	for i := 0; i < 3; i++ {
-		caches = append(caches, make(cache))
+func readInput(r io.Reader) (codes []string) {
+	s := bufio.NewScanner(r)
+
+	for s.Scan() {
+		codes = append(codes, s.Text())
 	}
 
+	return
+}
+
+func complexity(codes []string, chain *layer) (total int) {
 	for _, code := range codes {
-		steps := cascadingOptimalPath(code, NUMERIC_KEYS, caches)
 		n, _ := strconv.Atoi(code[:len(code)-1])
-		total += steps * n
+		total += chain.cost(code) * n
 	}
 
 	return
 }
 
-func part2(codes []string) (total int) {
-	var caches []cache
-	for i := 0; i < 26; i++ {
-		caches = append(caches, make(cache))
+// Parse a key pad from its textual layout. Blank lines at the start and end of
+// the layout are ignored.
+func parseKeyPad(layout string) keyPad {
+	pad := make(keyPad)
+	for y, line := range strings.Split(strings.Trim(layout, "\n"), "\n") {
+		for x, key := range line {
+			if key == ' ' {
+				continue
+			}
+
+			if _, ok := pad[key]; ok {
+				panic(fmt.Sprintf("duplicate key %q", key))
+			}
+
+			pad[key] = point.New(x, y)
+		}
 	}
 
-	for _, code := range codes {
-		steps := cascadingOptimalPath(code, NUMERIC_KEYS, caches)
-		n, _ := strconv.Atoi(code[:len(code)-1])
-		total += steps * n
+	if _, ok := pad['A']; !ok {
+		panic("key pad has no 'A' key")
 	}
 
-	return
+	return pad
 }
 
-func readInput(r io.Reader) (codes []string) {
-	s := bufio.NewScanner(r)
+// The key at position `p` on the pad, and whether there is one.
+func (k keyPad) keyAt(p point.Point) (rune, bool) {
+	for key, q := range k {
+		if p == q {
+			return key, true
+		}
+	}
 
-	for s.Scan() {
-		codes = append(codes, s.Text())
+	return 0, false
+}
+
+// The keys on the pad, in a stable order.
+func (k keyPad) keys() []rune {
+	keys := make([]rune, 0, len(k))
+	for key := range k {
+		keys = append(keys, key)
 	}
 
-	return
+	slices.Sort(keys)
+	return keys
 }
 
-func cascadingOptimalPath(
-	code string,
-	pad keyPad,
-	caches []cache,
-) int {
-	if len(caches) == 0 {
-		return len(code)
+// Build a chain of robots where the innermost robot presses keys on `pad`, and
+// each robot is directed from a `ctrl` key pad. There are `depth` of these
+// directional key pads, the last of which is pressed by the human.
+func newChain(pad, ctrl keyPad, depth int) *layer {
+	l := &layer{ctrl, nil, make(map[move]plan)}
+	for _, from := range ctrl.keys() {
+		for _, to := range ctrl.keys() {
+			l.moves[move{from, to}] = plan{1, ""}
+		}
+	}
+
+	for i := 1; i < depth; i++ {
+		l = newLayer(ctrl, l)
 	}
 
-	return optimalPath(code, pad, caches[0], func(step string) int {
-		return cascadingOptimalPath(step, DIRECTION_KEYS, caches[1:])
-	})
+	return newLayer(pad, l)
 }
 
-func optimalPath(
-	code string,
-	pad keyPad,
-	cache cache,
-	plan func(string) int,
-) (minLen int) {
-	curr := 'A'
+// Plan the cheapest way to press each key on `pad` starting from each other
+// key, when its arm is directed by presses on `ctrl`. This is a shortest path
+// search over the position of both arms, where moving this layer's arm in some
+// direction costs the presses needed to push that direction on `ctrl`, and the
+// final press costs the presses needed to push 'A'. It makes no assumptions
+// about the shape of `pad`, so it is free to take detours around gaps.
+func newLayer(pad keyPad, ctrl *layer) *layer {
+	l := &layer{pad, ctrl, make(map[move]plan)}
+
+	for _, from := range pad.keys() {
+		start := config{pad[from], 'A'}
+		dists := map[config]int{start: 0}
+		prevs := make(map[config]config)
+		done := make(map[config]bool)
+
+		for {
+			// Find the closest unvisited configuration. Key pads are small enough
+			// that a linear scan is cheaper than maintaining a heap.
+			curr, dist := config{}, math.MaxInt
+			for c, d := range dists {
+				if !done[c] && (d < dist || (d == dist && less(c, curr))) {
+					curr, dist = c, d
+				}
+			}
+
+			if dist == math.MaxInt {
+				break
+			}
+
+			done[curr] = true
+			for _, dir := range ctrl.pad.keys() {
+				v, ok := DIRECTIONS[dir]
+				if !ok {
+					continue
+				}
+
+				next := config{curr.pos.Move(v), dir}
+				if _, ok := pad.keyAt(next.pos); !ok {
+					continue
+				}
+
+				d := dist + ctrl.moves[move{curr.ctrl, dir}].cost
+				if prev, ok := dists[next]; !ok || d < prev {
+					dists[next] = d
+					prevs[next] = curr
+				}
+			}
+		}
 
+		for _, to := range pad.keys() {
+			best, cost := config{}, math.MaxInt
+			for c, d := range dists {
+				if c.pos != pad[to] {
+					continue
+				}
+
+				d += ctrl.moves[move{c.ctrl, 'A'}].cost
+				if d < cost || (d == cost && less(c, best)) {
+					best, cost = c, d
+				}
+			}
+
+			if cost == math.MaxInt {
+				panic(fmt.Sprintf("cannot reach %q from %q", to, from))
+			}
+
+			presses := []rune{'A'}
+			for c := best; c != start; c = prevs[c] {
+				presses = append(presses, c.ctrl)
+			}
+
+			slices.Reverse(presses)
+			l.moves[move{from, to}] = plan{cost, string(presses)}
+		}
+	}
+
+	return l
+}
+
+// The number of keys the human needs to press to type `code` on this layer's
+// key pad, starting with every arm pointing at 'A'.
+func (l *layer) cost(code string) (total int) {
+	curr := 'A'
 	for _, next := range code {
-		minLen += optimalStep(curr, next, pad, cache, plan)
+		total += l.moves[move{curr, next}].cost
 		curr = next
 	}
 
 	return
 }
 
-func optimalStep(
-	curr, next rune,
-	pad keyPad,
-	cache cache,
-	plan func(code string) int,
-) int {
-	corner := pad['X']
-	from, to := pad[curr], pad[next]
-	v := to.Sub(from)
+// A cheapest sequence of keys the human can press to type `code` on this
+// layer's key pad. The sequence grows exponentially with the depth of the
+// chain, so this is only practical for short chains.
+func (l *layer) sequence(code string) string {
+	var b strings.Builder
+	l.expand(code, &b)
+	return b.String()
+}
 
-	var bias bias
-	switch {
-	case from.X == corner.X && to.Y == corner.Y:
-		bias = HORZ
-	case from.Y == corner.Y && to.X == corner.X:
-		bias = VERT
-	default:
-		bias = NONE
+func (l *layer) expand(code string, b *strings.Builder) {
+	if l.ctrl == nil {
+		b.WriteString(code)
+		return
 	}
 
-	key := cacheKey{v, bias}
-	if steps, ok := cache[key]; ok {
-		return steps
+	curr := 'A'
+	for _, next := range code {
+		l.ctrl.expand(l.moves[move{curr, next}].presses, b)
+		curr = next
 	}
+}
 
-	var x, y string
-	if v.Dy < 0 {
-		y = strings.Repeat("^", -v.Dy)
-	} else {
-		y = strings.Repeat("v", v.Dy)
+// Set up a chain of robots, all pointing at 'A', where the first robot presses
+// keys on `pad`, and is directed by `depth - 1` robots pressing keys on `ctrl`,
+// directed by the human pressing keys on another `ctrl` key pad.
+func newSimulator(pad, ctrl keyPad, depth int) *simulator {
+	s := &simulator{[]keyPad{pad}, []point.Point{pad['A']}}
+	for i := 1; i < depth; i++ {
+		s.pads = append(s.pads, ctrl)
+		s.arms = append(s.arms, ctrl['A'])
 	}
 
-	if v.Dx < 0 {
-		x = strings.Repeat("<", -v.Dx)
-	} else {
-		x = strings.Repeat(">", v.Dx)
-	}
+	return s
+}
 
-	minLen := math.MaxInt
-	var b strings.Builder
+// Replay the key presses made by the human, returning the code typed by the
+// first robot. If a robot panics, returns the code typed up to that point,
+// along with a `*panicError` describing it.
+func (s *simulator) replay(presses string) (string, error) {
+	var typed strings.Builder
+	for i, key := range presses {
+		out, ok, err := s.press(i, key)
+		if err != nil {
+			return typed.String(), err
+		}
 
-	if bias&HORZ != 0 {
-		b.Reset()
-		b.WriteString(x)
-		b.WriteString(y)
-		b.WriteRune('A')
-		lenXY := plan(b.String())
-		if lenXY < minLen {
-			minLen = lenXY
+		if ok {
+			typed.WriteRune(out)
 		}
 	}
 
-	if bias&VERT != 0 {
-		b.Reset()
-		b.WriteString(y)
-		b.WriteString(x)
-		b.WriteRune('A')
-		lenYX := plan(b.String())
-		if lenYX < minLen {
-			minLen = lenYX
+	return typed.String(), nil
+}
+
+// Press `key` on the human's key pad, as the `i`-th press, and pass the
+// instruction down the chain. Returns the key typed by the first robot if the
+// press made it all the way down the chain.
+func (s *simulator) press(i int, key rune) (typed rune, ok bool, err error) {
+	for robot := len(s.pads) - 1; robot >= 0; robot-- {
+		pad, arm := s.pads[robot], &s.arms[robot]
+		if key == 'A' {
+			key, _ = pad.keyAt(*arm)
+			continue
 		}
+
+		v, ok := DIRECTIONS[key]
+		if !ok {
+			return 0, false, fmt.Errorf("robot %d given unrecognised instruction %q on press %d", robot, key, i)
+		}
+
+		*arm = arm.Move(v)
+		if _, ok := pad.keyAt(*arm); !ok {
+			return 0, false, &panicError{robot, i, *arm}
+		}
+
+		return 0, false, nil
+	}
+
+	return key, true, nil
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("robot %d panicked at %d,%d on press %d", e.robot, e.pos.X, e.pos.Y, e.press)
+}
+
+// A total order on configurations, used to break ties deterministically.
+func less(a, b config) bool {
+	if a.pos.Y != b.pos.Y {
+		return a.pos.Y < b.pos.Y
+	}
+
+	if a.pos.X != b.pos.X {
+		return a.pos.X < b.pos.X
 	}
 
-	cache[key] = minLen
-	return minLen
+	return a.ctrl < b.ctrl
 }
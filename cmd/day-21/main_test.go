@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"internal/point"
+	"testing"
+)
+
+var exampleCodes = []string{"029A", "980A", "179A", "456A", "379A"}
+
+func TestExample(t *testing.T) {
+	if actual := part1(exampleCodes); actual != 126384 {
+		t.Errorf("expected 126384, got %d", actual)
+	}
+}
+
+func TestSequenceRoundTrip(t *testing.T) {
+	expect := map[string]int{"029A": 68, "980A": 60, "179A": 68, "456A": 64, "379A": 64}
+
+	for depth := 1; depth <= 4; depth++ {
+		chain := newChain(NUMERIC_KEYS, DIRECTION_KEYS, depth)
+		for _, code := range exampleCodes {
+			presses := chain.sequence(code)
+			if len(presses) != chain.cost(code) {
+				t.Errorf("depth %d, %s: sequence has length %d, but cost is %d",
+					depth, code, len(presses), chain.cost(code))
+			}
+
+			if depth == PART1_DEPTH && len(presses) != expect[code] {
+				t.Errorf("%s: expected %d presses, got %d", code, expect[code], len(presses))
+			}
+
+			typed, err := newSimulator(NUMERIC_KEYS, DIRECTION_KEYS, depth).replay(presses)
+			if err != nil {
+				t.Errorf("depth %d, %s: %v", depth, code, err)
+			} else if typed != code {
+				t.Errorf("depth %d: expected to type %s, got %s", depth, code, typed)
+			}
+		}
+	}
+}
+
+func TestIrregularKeyPad(t *testing.T) {
+	// A key pad with several gaps, where getting from '1' to '2' requires a
+	// detour through the bottom row.
+	pad := parseKeyPad(`
+1 2
+3 4
+5A6
+`)
+
+	chain := newChain(pad, DIRECTION_KEYS, 2)
+	for _, code := range []string{"12A", "6142A", "5A"} {
+		presses := chain.sequence(code)
+		typed, err := newSimulator(pad, DIRECTION_KEYS, 2).replay(presses)
+		if err != nil {
+			t.Errorf("%s: %v", code, err)
+		} else if typed != code {
+			t.Errorf("expected to type %s, got %s", code, typed)
+		}
+	}
+
+	// Directly from '1', the only route to '2' is down to the bottom row, across
+	// and back up: 2 + 2 + 2 moves, plus the press.
+	direct := newChain(pad, DIRECTION_KEYS, 1)
+	if actual := direct.moves[move{'1', '2'}].presses; len(actual) != 7 {
+		t.Errorf("expected a 7 key detour from '1' to '2', got %q", actual)
+	}
+}
+
+func TestCustomController(t *testing.T) {
+	// A controller laid out in a single column, which only has vertical
+	// directions, can still direct a key pad laid out in a column.
+	ctrl := parseKeyPad(`
+^
+A
+v
+`)
+
+	pad := parseKeyPad(`
+1
+2
+A
+`)
+
+	chain := newChain(pad, ctrl, 2)
+	for _, code := range []string{"12A", "21A", "A"} {
+		presses := chain.sequence(code)
+		typed, err := newSimulator(pad, ctrl, 2).replay(presses)
+		if err != nil {
+			t.Errorf("%s: %v", code, err)
+		} else if typed != code {
+			t.Errorf("expected to type %s, got %s", code, typed)
+		}
+	}
+}
+
+func TestControllerMissingDirection(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected a panic")
+		}
+	}()
+
+	// The controller has no '<' key, so the arm can never reach '1'.
+	ctrl := parseKeyPad(`
+^
+A
+v
+`)
+
+	newChain(parseKeyPad("1A\n"), ctrl, 1)
+}
+
+func TestReplayExample(t *testing.T) {
+	// The sequence from the puzzle description for typing 029A.
+	presses := "<vA<AA>>^AvAA<^A>A<v<A>>^AvA^A<vA>^A<v<A>^A>AAvA^A<v<A>A>^AAAvA<^A>A"
+	typed, err := newSimulator(NUMERIC_KEYS, DIRECTION_KEYS, 3).replay(presses)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if typed != "029A" {
+		t.Errorf("expected 029A, got %s", typed)
+	}
+}
+
+func TestReplayPanic(t *testing.T) {
+	for _, tc := range []struct {
+		presses string
+		depth   int
+		typed   string
+		expect  panicError
+	}{
+		// Straight into the gap on the numeric key pad.
+		{"<A<<A", 1, "0", panicError{0, 2, point.New(0, 3)}},
+		// Into the gap on the directional key pad, and off its edge, without the
+		// numeric robot ever moving.
+		{"<<A", 2, "", panicError{1, 1, point.New(0, 0)}},
+		{"v<<<", 2, "", panicError{1, 3, point.New(-1, 1)}},
+		// The numeric robot's arm moving off the top of its key pad.
+		{"<AAAA", 2, "", panicError{0, 4, point.New(2, -1)}},
+	} {
+		typed, err := newSimulator(NUMERIC_KEYS, DIRECTION_KEYS, tc.depth).replay(tc.presses)
+
+		var actual *panicError
+		if !errors.As(err, &actual) {
+			t.Errorf("%s: expected a panic, got %v", tc.presses, err)
+			continue
+		}
+
+		if *actual != tc.expect {
+			t.Errorf("%s: expected %+v, got %+v", tc.presses, tc.expect, *actual)
+		}
+
+		if typed != tc.typed {
+			t.Errorf("%s: expected to type %q before panicking, got %q", tc.presses, tc.typed, typed)
+		}
+	}
+}
@@ -1,20 +1,74 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"io"
 	"os"
+	"runtime"
+	"sync"
 )
 
+// An encoding of the last four price changes, as a number in base 19, with
+// each digit offset by 9 (so that a change of -9 is encoded as 0).
+type signature uint32
+
 type round struct {
-	signature uint32
+	signature signature
 	price     int
 }
 
+// The outcome of instructing the monkey to sell on a particular sequence of
+// price changes.
+type sale struct {
+	signature signature
+
+	// The total bananas earned from all buyers.
+	total int
+
+	// The price each buyer sold at, and whether they sold at all: Buyers whose
+	// prices never followed the sequence don't sell, and their price is 0.
+	prices []int
+	sold   []bool
+}
+
+const (
+	ROUNDS     = 2000
+	SIGNATURES = 19 * 19 * 19 * 19
+)
+
+var (
+	explain = flag.Bool("explain", false, "print the best sequence of price changes, and the price each buyer sold at")
+	nth     = flag.Uint64("nth", 0, "print each buyer's nth secret, its predecessor, and the length of its cycle")
+)
+
 func main() {
+	flag.Parse()
+
 	hashes := readInput(os.Stdin)
 	fmt.Println("Part 1:", part1(hashes))
-	fmt.Println("Part 2:", part2(hashes))
+
+	best := bestSale(hashes)
+	fmt.Println("Part 2:", best.total)
+
+	if *explain {
+		fmt.Println("Sequence:", best.signature)
+		for i, price := range best.prices {
+			if best.sold[i] {
+				fmt.Printf("  %d: %d\n", hashes[i], price)
+			} else {
+				fmt.Printf("  %d: did not sell\n", hashes[i])
+			}
+		}
+	}
+
+	if *nth > 0 {
+		for _, seed := range hashes {
+			s := jump(seed, *nth)
+			fmt.Printf("%d: secret %d is %d, after %d, cycle length %d\n",
+				seed, *nth, s, prev(s), cycleLength(seed))
+		}
+	}
 }
 
 func readInput(r io.Reader) (hs []uint32) {
@@ -32,7 +86,7 @@ func readInput(r io.Reader) (hs []uint32) {
 func part1(seeds []uint32) (sum int) {
 	for _, seed := range seeds {
 		for i, hash := range hashes(seed) {
-			if i == 2000 {
+			if i == ROUNDS {
 				sum += int(hash)
 				break
 			}
@@ -41,27 +95,81 @@ func part1(seeds []uint32) (sum int) {
 	return
 }
 
-func part2(seeds []uint32) (bestPrice int) {
-	bananas := make(map[uint32]int)
-	for _, seed := range seeds {
-		seen := make(map[uint32]struct{})
-		for i, round := range rounds(seed) {
-			if i >= 2000 {
-				break
-			}
+func part2(seeds []uint32) int {
+	return bestSale(seeds).total
+}
+
+// Find the sequence of price changes that earns the most bananas across all
+// buyers. Buyers are shared out between workers, each of which tallies the
+// bananas earned for every possible sequence in its own dense table, to avoid
+// contention. The tables are combined at the end.
+func bestSale(seeds []uint32) (best sale) {
+	workers := runtime.GOMAXPROCS(0)
+	tables := make([][]int, workers)
 
-			if _, ok := seen[round.signature]; ok {
-				continue
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// `seen` records the last buyer (offset by one) that had each
+			// signature, so that only the first sale per buyer is counted, without
+			// having to clear the table between buyers.
+			bananas := make([]int, SIGNATURES)
+			seen := make([]int, SIGNATURES)
+			for b := w; b < len(seeds); b += workers {
+				for i, round := range rounds(seeds[b]) {
+					if i >= ROUNDS {
+						break
+					}
+
+					if seen[round.signature] == b+1 {
+						continue
+					}
+
+					seen[round.signature] = b + 1
+					bananas[round.signature] += round.price
+				}
 			}
 
-			seen[round.signature] = struct{}{}
-			bananas[round.signature] += round.price
+			tables[w] = bananas
+		}()
+	}
+
+	wg.Wait()
+
+	for s := range SIGNATURES {
+		total := 0
+		for _, bananas := range tables {
+			total += bananas[s]
+		}
+
+		if total > best.total {
+			best.signature, best.total = signature(s), total
 		}
 	}
 
-	for _, price := range bananas {
-		if price > bestPrice {
-			bestPrice = price
+	best.prices = make([]int, len(seeds))
+	best.sold = make([]bool, len(seeds))
+	for b, seed := range seeds {
+		best.prices[b], best.sold[b] = salePrice(seed, best.signature)
+	}
+
+	return
+}
+
+// The price the buyer with the given seed sells at, when the monkey is
+// instructed to sell after the price changes encoded in `sig`, and whether
+// they sell at all (they don't if they never see those changes).
+func salePrice(seed uint32, sig signature) (price int, sold bool) {
+	for i, round := range rounds(seed) {
+		if i >= ROUNDS {
+			break
+		}
+
+		if round.signature == sig {
+			return round.price, true
 		}
 	}
 
@@ -86,9 +194,9 @@ func rounds(s uint32) func(yield func(int, round) bool) {
 
 		update := func() {
 			p := int(h % 10)
-			d := byte(p - r.price)
+			d := signature(p - r.price + 9)
 
-			r.signature = (r.signature << 8) | 0xff&uint32(d)
+			r.signature = (r.signature*19 + d) % SIGNATURES
 			r.price = p
 
 			h = next(h)
@@ -130,6 +238,20 @@ func next(i uint32) uint32 {
 	return i
 }
 
+// The four price changes encoded in the signature, oldest first.
+func (s signature) changes() (cs [4]int) {
+	for i := 3; i >= 0; i-- {
+		cs[i] = int(s%19) - 9
+		s /= 19
+	}
+	return
+}
+
+func (s signature) Format(f fmt.State, _ rune) {
+	cs := s.changes()
+	fmt.Fprintf(f, "%d,%d,%d,%d", cs[0], cs[1], cs[2], cs[3])
+}
+
 func (r round) Format(f fmt.State, _ rune) {
-	fmt.Fprintf(f, "%08x -> %d", r.signature, r.price)
+	fmt.Fprintf(f, "%v -> %d", r.signature, r.price)
 }
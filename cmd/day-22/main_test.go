@@ -0,0 +1,129 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestExample(t *testing.T) {
+	if actual := part1([]uint32{1, 10, 100, 2024}); actual != 37327623 {
+		t.Errorf("part 1: expected 37327623, got %d", actual)
+	}
+
+	best := bestSale([]uint32{1, 2, 3, 2024})
+	if best.total != 23 {
+		t.Errorf("part 2: expected 23, got %d", best.total)
+	}
+
+	if cs := best.signature.changes(); cs != [4]int{-2, 1, -1, 3} {
+		t.Errorf("expected sequence -2,1,-1,3, got %v", cs)
+	}
+
+	if !slices.Equal(best.prices, []int{7, 7, 0, 9}) {
+		t.Errorf("expected prices [7 7 0 9], got %v", best.prices)
+	}
+
+	if !slices.Equal(best.sold, []bool{true, true, false, true}) {
+		t.Errorf("expected only the third buyer not to sell, got %v", best.sold)
+	}
+}
+
+func TestSalePriceZero(t *testing.T) {
+	// Selling at a price of 0 is distinct from not selling at all.
+	seen := make(map[signature]bool)
+	for i, r := range rounds(123) {
+		if i >= ROUNDS {
+			t.Fatalf("expected seed 123 to reach a price of 0")
+		}
+
+		if r.price == 0 && !seen[r.signature] {
+			if price, sold := salePrice(123, r.signature); price != 0 || !sold {
+				t.Errorf("expected to sell at 0, got %d (sold: %v)", price, sold)
+			}
+			break
+		}
+
+		seen[r.signature] = true
+	}
+
+	if _, sold := salePrice(123, 0); sold {
+		t.Errorf("expected not to sell on a sequence of four -9s")
+	}
+}
+
+func TestSignature(t *testing.T) {
+	// The price sequence from the puzzle description, for seed 123, starting
+	// from the first round that `rounds` produces.
+	expect := []struct {
+		changes [4]int
+		price   int
+	}{
+		{[4]int{6, -1, -1, 0}, 4},
+		{[4]int{-1, -1, 0, 2}, 6},
+		{[4]int{-1, 0, 2, -2}, 4},
+		{[4]int{0, 2, -2, 0}, 4},
+		{[4]int{2, -2, 0, -2}, 2},
+	}
+
+	for i, r := range rounds(123) {
+		if i-5 >= len(expect) {
+			break
+		}
+
+		e := expect[i-5]
+		if cs := r.signature.changes(); cs != e.changes || r.price != e.price {
+			t.Errorf("round %d: expected %v -> %d, got %v", i, e.changes, e.price, r)
+		}
+	}
+}
+
+func TestJump(t *testing.T) {
+	// Including seeds with more than 24 bits, which the first step truncates.
+	for _, seed := range []uint32{1, 10, 100, 2024, 123, 1<<24 + 123, 1<<32 - 1} {
+		for i, hash := range hashes(seed) {
+			if i > 2000 {
+				break
+			}
+
+			if actual := jump(seed, uint64(i)); actual != hash {
+				t.Errorf("%d after %d steps: expected %d, got %d", seed, i, hash, actual)
+			}
+		}
+	}
+}
+
+func TestPrev(t *testing.T) {
+	for _, s := range []uint32{0, 1, 123, 2024, 15887950, 1<<24 - 1} {
+		if actual := prev(next(s)); actual != s {
+			t.Errorf("expected prev(next(%d)) = %d, got %d", s, s, actual)
+		}
+
+		if actual := next(prev(s)); actual != s {
+			t.Errorf("expected next(prev(%d)) = %d, got %d", s, s, actual)
+		}
+	}
+}
+
+func TestCycleLength(t *testing.T) {
+	if actual := cycleLength(0); actual != 1 {
+		t.Errorf("expected 0 to be a fixed point, got cycle of %d", actual)
+	}
+
+	// Find the cycle length for one seed the slow way.
+	seed, expect := uint32(123), 1
+	for s := next(seed); s != seed; s = next(s) {
+		expect++
+	}
+
+	if actual := cycleLength(seed); actual != expect {
+		t.Errorf("expected cycle of %d, got %d", expect, actual)
+	}
+
+	if actual := jump(seed, uint64(expect)); actual != seed {
+		t.Errorf("expected to return to %d after a full cycle, got %d", seed, actual)
+	}
+
+	if actual := cycleLength(1<<24 + seed); actual != expect {
+		t.Errorf("expected a seed beyond 24 bits to join the cycle of %d, got %d", expect, actual)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+// The secret number generator is linear over GF(2)^24: each step only shifts
+// and XORs bits, and truncates to 24 bits. It can therefore be represented as
+// a 24x24 bit matrix, which supports jumping ahead by any number of steps in
+// logarithmic time, and stepping backwards.
+//
+// Matrices are stored as their columns: column `j` is the image of the `j`-th
+// basis vector (the number with only bit `j` set).
+type bitMatrix [BITS]uint32
+
+const (
+	BITS = 24
+
+	// How many baby steps to take when searching for a cycle. Cycles are at
+	// most 2^24 long, so this many giant steps of the same size are enough to
+	// cover any cycle.
+	BABY_STEPS = 1 << (BITS / 2)
+)
+
+// The matrix representing a single step of `next`.
+func generator() (m bitMatrix) {
+	for j := range m {
+		m[j] = next(1 << j)
+	}
+	return
+}
+
+func identity() (m bitMatrix) {
+	for j := range m {
+		m[j] = 1 << j
+	}
+	return
+}
+
+// Multiply `v` by the matrix, XOR-ing together the columns that correspond to
+// bits set in `v`. Only the low 24 bits of `v` take part, as in `next`.
+func (m *bitMatrix) apply(v uint32) (w uint32) {
+	v %= 1 << BITS
+	for j := 0; v != 0; j, v = j+1, v>>1 {
+		if v&1 != 0 {
+			w ^= m[j]
+		}
+	}
+	return
+}
+
+// The matrix that applies `n` first, then `m`.
+func (m *bitMatrix) mul(n *bitMatrix) (p bitMatrix) {
+	for j := range p {
+		p[j] = m.apply(n[j])
+	}
+	return
+}
+
+// Raise the matrix to the `k`-th power by repeated squaring.
+func (m *bitMatrix) pow(k uint64) bitMatrix {
+	p, sq := identity(), *m
+	for ; k > 0; k >>= 1 {
+		if k&1 != 0 {
+			p = sq.mul(&p)
+		}
+		sq = sq.mul(&sq)
+	}
+	return p
+}
+
+// Invert the matrix by Gauss-Jordan elimination, reducing the matrix to the
+// identity by column operations, and applying the same operations to the
+// identity. Returns whether the matrix is invertible.
+func (m *bitMatrix) inverse() (bitMatrix, bool) {
+	a, inv := *m, identity()
+
+	for bit := 0; bit < BITS; bit++ {
+		// Find a column (at or after the current one) with the current bit set,
+		// and move it into place.
+		pivot := -1
+		for j := bit; j < BITS; j++ {
+			if a[j]>>bit&1 != 0 {
+				pivot = j
+				break
+			}
+		}
+
+		if pivot < 0 {
+			return bitMatrix{}, false
+		}
+
+		a[bit], a[pivot] = a[pivot], a[bit]
+		inv[bit], inv[pivot] = inv[pivot], inv[bit]
+
+		// Clear the current bit from every other column.
+		for j := range a {
+			if j != bit && a[j]>>bit&1 != 0 {
+				a[j] ^= a[bit]
+				inv[j] ^= inv[bit]
+			}
+		}
+	}
+
+	return inv, true
+}
+
+// The secret `n` steps after `seed`.
+func jump(seed uint32, n uint64) uint32 {
+	// Any bits of the seed beyond the 24th are dropped by the first step.
+	if n == 0 {
+		return seed
+	}
+
+	g := generator()
+	m := g.pow(n)
+	return m.apply(seed)
+}
+
+// The secret that `next` turns into `s`.
+func prev(s uint32) uint32 {
+	g := generator()
+	inv, ok := g.inverse()
+	if !ok {
+		panic("generator is not invertible")
+	}
+
+	return inv.apply(s)
+}
+
+// The length of the cycle of secrets that `seed` is part of. The generator is
+// invertible, so every secret is part of a cycle.
+//
+// Uses baby-step giant-step: the cycle length is the smallest `k = i * B + j`
+// (with `1 <= j <= B`) such that stepping `seed` forward `j` times gives the
+// same secret as stepping it backwards `i * B` times.
+//
+// Seeds beyond 24 bits never come round again, but their first step is the
+// same as for their low 24 bits, which are part of a cycle, so the length of
+// that cycle is returned.
+func cycleLength(seed uint32) int {
+	seed %= 1 << BITS
+	baby := make(map[uint32]int, BABY_STEPS)
+	for j, s := 1, next(seed); j <= BABY_STEPS; j, s = j+1, next(s) {
+		if _, ok := baby[s]; !ok {
+			baby[s] = j
+		}
+	}
+
+	g := generator()
+	inv, ok := g.inverse()
+	if !ok {
+		panic("generator is not invertible")
+	}
+
+	giant := inv.pow(BABY_STEPS)
+	for i, s := 0, seed; i <= BABY_STEPS; i, s = i+1, giant.apply(s) {
+		if j, ok := baby[s]; ok {
+			return i*BABY_STEPS + j
+		}
+	}
+
+	panic("no cycle found")
+}
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"internal/graph"
+	"internal/set"
 	"io"
 	"os"
 	"slices"
@@ -34,8 +37,45 @@ type node struct {
 
 type network map[string]*node
 
+// Pairs of wires whose outputs were swapped, found by inspecting the renamed
+// network printed by `part2`.
+var SWAPS = map[string]string{
+	"bmn": "z23",
+	"jss": "rds",
+	"mvb": "z08",
+	"rds": "jss",
+	"wss": "z18",
+	"z08": "mvb",
+	"z18": "wss",
+	"z23": "bmn",
+}
+
+var export = flag.String("export", "", "write the wiring as a graph in this format (dot, adj or csv) instead of solving, highlighting swapped wires")
+
 func main() {
+	flag.Parse()
+
 	n := readInput(os.Stdin)
+	if *export != "" {
+		f, err := graph.ParseFormat(*export)
+		if err != nil {
+			panic(err)
+		}
+
+		g := n.graph()
+		swapped := set.New[string]()
+		for w := range SWAPS {
+			if g.HasNode(w) {
+				swapped.Add(w)
+			}
+		}
+
+		if err := g.Export(os.Stdout, f, g.Induced(swapped)); err != nil {
+			panic(err)
+		}
+		return
+	}
+
 	n.propagate()
 
 	fmt.Println("Part 1:", part1(n))
@@ -60,17 +100,7 @@ func part1(n network) (val int) {
 }
 
 func part2(n network) {
-	r := make(map[string]string)
-	r["bmn"] = "z23"
-	r["jss"] = "rds"
-	r["mvb"] = "z08"
-	r["rds"] = "jss"
-	r["wss"] = "z18"
-	r["z08"] = "mvb"
-	r["z18"] = "wss"
-	r["z23"] = "bmn"
-
-	m, rename := adderRename(n.rewired(r))
+	m, rename := adderRename(n.rewired(SWAPS))
 	fmt.Println(rename)
 	fmt.Println(m)
 }
@@ -242,6 +272,27 @@ func (n network) propagate() {
 	}
 }
 
+// Convert the network into a directed graph, with an edge from each gate's
+// inputs to its output wire.
+func (n network) graph() *graph.Graph[string] {
+	wires := make([]string, 0, len(n))
+	for w := range n {
+		wires = append(wires, w)
+	}
+
+	slices.Sort(wires)
+
+	g := graph.NewDirected[string]()
+	for _, w := range wires {
+		g.AddNode(w)
+		for _, i := range n[w].inputs {
+			g.AddEdge(i, w)
+		}
+	}
+
+	return g
+}
+
 func (n network) renamed(r map[string]string) network {
 	rename := func(x string) string {
 		if y, ok := r[x]; ok {
@@ -3,13 +3,21 @@ module github.com/amnn/adventofcode-2024
 go 1.23.1
 
 require (
+	internal/graph v0.0.0
 	internal/grid v0.0.0
+	internal/linalg v0.0.0
 	internal/point v0.0.0
 	internal/set v0.0.0
+	internal/trie v0.0.0
+	internal/unionfind v0.0.0
 )
 
 replace (
+	internal/graph => ./internal/graph
 	internal/grid => ./internal/grid
+	internal/linalg => ./internal/linalg
 	internal/point => ./internal/point
 	internal/set => ./internal/set
+	internal/trie => ./internal/trie
+	internal/unionfind => ./internal/unionfind
 )
@@ -0,0 +1,219 @@
+package graph
+
+import (
+	"internal/set"
+)
+
+// Returns every triangle in the graph (three nodes that are all adjacent to
+// each other, ignoring the direction of edges) exactly once, with nodes in the
+// order they were added to the graph.
+func (g *Graph[N]) Triangles() func(yield func([3]N) bool) {
+	return func(yield func([3]N) bool) {
+		for i, a := range g.nodes {
+			as := g.adjacent(a)
+			for _, b := range g.sorted(as) {
+				if g.index[b] <= i {
+					continue
+				}
+
+				for _, c := range g.sorted(as.Intersect(g.adjacent(b))) {
+					if g.index[c] <= g.index[b] {
+						continue
+					}
+
+					if !yield([3]N{a, b, c}) {
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+// Returns every clique of exactly `k` nodes exactly once, with nodes in the
+// order they were added to the graph. Cliques are extended one node at a
+// time, only ever adding nodes that come later than all the nodes already in
+// the clique, and that are adjacent to all of them.
+func (g *Graph[N]) KCliques(k int) func(yield func([]N) bool) {
+	return func(yield func([]N) bool) {
+		if k <= 0 {
+			return
+		}
+
+		clique := make([]N, 0, k)
+
+		var extend func(candidates []N) bool
+		extend = func(candidates []N) bool {
+			if len(clique) == k {
+				return yield(append([]N(nil), clique...))
+			}
+
+			for i, n := range candidates {
+				// Not enough candidates left to complete the clique.
+				if len(clique)+len(candidates)-i < k {
+					break
+				}
+
+				adj := g.adjacent(n)
+				var next []N
+				for _, m := range candidates[i+1:] {
+					if adj.Contains(m) {
+						next = append(next, m)
+					}
+				}
+
+				clique = append(clique, n)
+				if !extend(next) {
+					return false
+				}
+				clique = clique[:len(clique)-1]
+			}
+
+			return true
+		}
+
+		extend(g.nodes)
+	}
+}
+
+// Return all maximal cliques in the graph, ignoring the direction of edges. A
+// maximal clique is one that is not contained in some other clique.
+//
+// This uses the Bron-Kerbosch algorithm, which grows a clique `r` from a set
+// of candidates `p` that could extend it, while tracking nodes `x` that have
+// already been tried (so that cliques are not reported twice, and cliques that
+// could be extended by a node from `x` are not reported at all). Two
+// refinements keep the search small:
+//
+//   - At each step it picks a pivot `u` from `p` or `x` with the most
+//     neighbours in `p`. Any maximal clique must contain a node that is not a
+//     neighbour of `u`, so only those nodes need to be tried.
+//   - At the top level, nodes are tried in degeneracy order, so that the
+//     candidate sets passed to each recursive call are at most as large as the
+//     graph's degeneracy.
+func (g *Graph[N]) MaximalCliques() (cliques []set.Set[N]) {
+	clique := set.New[N]()
+
+	var bronKerbosch func(p, x set.Set[N])
+	bronKerbosch = func(p, x set.Set[N]) {
+		if p.IsEmpty() && x.IsEmpty() {
+			cliques = append(cliques, clique.Copy())
+			return
+		}
+
+		// Ties between pivots are broken by the order nodes were added to the
+		// graph, so that cliques are always found in the same order.
+		var pivot N
+		most := -1
+		for _, s := range []set.Set[N]{p, x} {
+			for _, u := range g.sorted(s) {
+				if n := p.Intersect(g.adjacent(u)).Len(); n > most {
+					pivot, most = u, n
+				}
+			}
+		}
+
+		pivotAdj := g.adjacent(pivot)
+		for _, v := range g.sorted(p) {
+			if pivotAdj.Contains(v) {
+				continue
+			}
+
+			adj := g.adjacent(v)
+			clique.Add(v)
+			bronKerbosch(p.Intersect(adj), x.Intersect(adj))
+			clique.Remove(v)
+			p.Remove(v)
+			x.Add(v)
+		}
+	}
+
+	// Nodes later in the order are candidates, and nodes earlier in the order
+	// have already been tried.
+	order := g.DegeneracyOrder()
+	position := make(map[N]int, len(order))
+	for i, n := range order {
+		position[n] = i
+	}
+
+	for i, v := range order {
+		p, x := set.New[N](), set.New[N]()
+		for u := range g.adjacent(v) {
+			if position[u] > i {
+				p.Add(u)
+			} else {
+				x.Add(u)
+			}
+		}
+
+		clique.Add(v)
+		bronKerbosch(p, x)
+		clique.Remove(v)
+	}
+
+	return
+}
+
+// Return the largest clique in the graph, ignoring the direction of edges. If
+// there are multiple cliques of the largest size, the one found first is
+// returned.
+func (g *Graph[N]) MaximumClique() (largest set.Set[N]) {
+	largest = set.New[N]()
+	for _, c := range g.MaximalCliques() {
+		if c.Len() > largest.Len() {
+			largest = c
+		}
+	}
+	return
+}
+
+// Return the nodes of the graph in degeneracy order, ignoring the direction of
+// edges: repeatedly removing a node with the fewest remaining neighbours.
+// Every node has at most `d` neighbours later in the order, where `d` is the
+// graph's degeneracy (the smallest such bound over all orders).
+func (g *Graph[N]) DegeneracyOrder() []N {
+	degree := make(map[N]int, len(g.nodes))
+	buckets := make([][]N, 0)
+	for _, n := range g.nodes {
+		d := g.Degree(n)
+		degree[n] = d
+		for len(buckets) <= d {
+			buckets = append(buckets, nil)
+		}
+		buckets[d] = append(buckets[d], n)
+	}
+
+	// Buckets may contain stale entries for nodes whose degree has since
+	// dropped, which are skipped when popped.
+	removed := set.New[N]()
+	order := make([]N, 0, len(g.nodes))
+	for d := 0; len(order) < len(g.nodes); {
+		if len(buckets[d]) == 0 {
+			d++
+			continue
+		}
+
+		n := buckets[d][0]
+		buckets[d] = buckets[d][1:]
+		if removed.Contains(n) || degree[n] != d {
+			continue
+		}
+
+		removed.Add(n)
+		order = append(order, n)
+
+		for _, m := range g.sorted(g.adjacent(n)) {
+			if removed.Contains(m) {
+				continue
+			}
+
+			degree[m]--
+			buckets[degree[m]] = append(buckets[degree[m]], m)
+		}
+
+		// A neighbour's degree may now be one less than the current bucket.
+		d = max(0, d-1)
+	}
+
+	return order
+}
@@ -0,0 +1,415 @@
+package graph
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"internal/set"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Text formats that graphs can be exported to and imported from.
+type Format int
+
+// Part of a graph to draw attention to when exporting it. The zero value
+// highlights nothing.
+type Subgraph[N comparable] struct {
+	nodes set.Set[N]
+	edges set.Set[[2]N]
+}
+
+const (
+	// Graphviz DOT. Only the subset of the language written by `WriteDOT` can
+	// be read back.
+	DOT Format = iota
+
+	// One line per node, listing its successors: `a: b c`. Highlighted nodes
+	// and edges are marked with a trailing `*`: `a*: b* c`.
+	ADJACENCY
+
+	// CSV with a header, one row per edge: `source,target,highlight`.
+	EDGE_LIST
+)
+
+// Parse the name of a format, as accepted on the command line.
+func ParseFormat(name string) (Format, error) {
+	switch name {
+	case "dot":
+		return DOT, nil
+	case "adj":
+		return ADJACENCY, nil
+	case "csv":
+		return EDGE_LIST, nil
+	default:
+		return 0, fmt.Errorf("unknown graph format %q (expected dot, adj or csv)", name)
+	}
+}
+
+// The subgraph made up of `nodes`, and every edge in `g` between them.
+func (g *Graph[N]) Induced(nodes set.Set[N]) Subgraph[N] {
+	s := Subgraph[N]{set.New[N](), set.New[[2]N]()}
+	for a := range nodes {
+		s.nodes.Add(a)
+		for b := range g.out[a] {
+			if nodes.Contains(b) {
+				s.edges.Add([2]N{a, b})
+			}
+		}
+	}
+	return s
+}
+
+// The subgraph made up of `nodes`, and the edges from each node to the next,
+// wrapping around from the last node to the first.
+func Cycle[N comparable](nodes []N) Subgraph[N] {
+	s := Subgraph[N]{set.New[N](), set.New[[2]N]()}
+	for i, a := range nodes {
+		s.nodes.Add(a)
+		s.edges.Add([2]N{a, nodes[(i+1)%len(nodes)]})
+	}
+	return s
+}
+
+func (s Subgraph[N]) HasNode(n N) bool {
+	return s.nodes != nil && s.nodes.Contains(n)
+}
+
+// Check whether the subgraph contains the edge from `a` to `b`, in `g`. If `g`
+// is undirected, edges in the subgraph can match in either direction.
+func (s Subgraph[N]) hasEdge(g *Graph[N], a, b N) bool {
+	if s.edges == nil {
+		return false
+	}
+
+	return s.edges.Contains([2]N{a, b}) || (!g.directed && s.edges.Contains([2]N{b, a}))
+}
+
+// Write the graph to `w` in format `f`, highlighting `highlight` if the format
+// supports it.
+func (g *Graph[N]) Export(w io.Writer, f Format, highlight Subgraph[N]) error {
+	switch f {
+	case DOT:
+		return g.WriteDOT(w, highlight)
+	case ADJACENCY:
+		return g.WriteAdjacency(w, highlight)
+	case EDGE_LIST:
+		return g.WriteEdgeList(w, highlight)
+	default:
+		return fmt.Errorf("unknown graph format %d", f)
+	}
+}
+
+// Write the graph to `w` as a Graphviz DOT graph, with the nodes and edges in
+// `highlight` drawn in bold red. Node IDs are always quoted, following DOT's
+// rules, where the only escape sequence is `\"`. It is an error for a node's
+// name to end in a backslash, which would escape the closing quote, or to
+// contain a line break, which `ReadDOT` could not read back.
+func (g *Graph[N]) WriteDOT(w io.Writer, highlight Subgraph[N]) error {
+	const style = ` [color=red, penwidth=2]`
+
+	kind, arrow := "graph", "--"
+	if g.directed {
+		kind, arrow = "digraph", "->"
+	}
+
+	ids := make(map[N]string, len(g.nodes))
+	for _, n := range g.nodes {
+		id, err := quoteDOT(fmt.Sprint(n))
+		if err != nil {
+			return err
+		}
+		ids[n] = id
+	}
+
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, "%s {\n", kind)
+
+	for _, n := range g.nodes {
+		fmt.Fprintf(b, "\t%s", ids[n])
+		if highlight.HasNode(n) {
+			fmt.Fprint(b, style)
+		}
+		fmt.Fprintln(b, ";")
+	}
+
+	for s, t := range g.Edges() {
+		fmt.Fprintf(b, "\t%s %s %s", ids[s], arrow, ids[t])
+		if highlight.hasEdge(g, s, t) {
+			fmt.Fprint(b, style)
+		}
+		fmt.Fprintln(b, ";")
+	}
+
+	fmt.Fprintln(b, "}")
+	return b.Flush()
+}
+
+func quoteDOT(name string) (string, error) {
+	if strings.ContainsAny(name, "\r\n") || strings.HasSuffix(name, `\`) {
+		return "", fmt.Errorf("node %q cannot be written as a DOT ID", name)
+	}
+
+	return `"` + strings.ReplaceAll(name, `"`, `\"`) + `"`, nil
+}
+
+// Write the graph to `w` as an adjacency list, with a line per node listing
+// its successors, and a `*` after each node and edge in `highlight`. In an
+// undirected graph, each edge appears in the lines of both of its nodes. Node
+// names are separated by spaces, so it is an error for them to be empty, or
+// to contain spaces or ':', or to end in '*'.
+func (g *Graph[N]) WriteAdjacency(w io.Writer, highlight Subgraph[N]) error {
+	for _, n := range g.nodes {
+		if name := fmt.Sprint(n); name == "" || strings.ContainsAny(name, ": \t\r\n\v\f") || strings.HasSuffix(name, "*") {
+			return fmt.Errorf("node %q cannot be written in an adjacency list", name)
+		}
+	}
+
+	mark := func(highlighted bool) string {
+		if highlighted {
+			return "*"
+		}
+		return ""
+	}
+
+	b := bufio.NewWriter(w)
+	for _, n := range g.nodes {
+		fmt.Fprintf(b, "%v%s:", n, mark(highlight.HasNode(n)))
+		for _, m := range g.sorted(g.out[n]) {
+			fmt.Fprintf(b, " %v%s", m, mark(highlight.hasEdge(g, n, m)))
+		}
+		fmt.Fprintln(b)
+	}
+
+	return b.Flush()
+}
+
+// Write the edges of the graph to `w` as CSV, with a column marking the edges
+// in `highlight`. Nodes without any edges are not represented.
+func (g *Graph[N]) WriteEdgeList(w io.Writer, highlight Subgraph[N]) error {
+	c := csv.NewWriter(w)
+	c.Write([]string{"source", "target", "highlight"})
+	for s, t := range g.Edges() {
+		c.Write([]string{
+			fmt.Sprint(s),
+			fmt.Sprint(t),
+			strconv.FormatBool(highlight.hasEdge(g, s, t)),
+		})
+	}
+
+	c.Flush()
+	return c.Error()
+}
+
+// Read a graph from `r` in format `f`, using `parse` to convert node names
+// into nodes. DOT graphs declare whether they are directed, otherwise
+// `directed` decides.
+func Import[N comparable](
+	r io.Reader,
+	f Format,
+	directed bool,
+	parse func(string) (N, error),
+) (*Graph[N], error) {
+	switch f {
+	case DOT:
+		return ReadDOT(r, parse)
+	case ADJACENCY:
+		return ReadAdjacency(r, directed, parse)
+	case EDGE_LIST:
+		return ReadEdgeList(r, directed, parse)
+	default:
+		return nil, fmt.Errorf("unknown graph format %d", f)
+	}
+}
+
+// Read a graph written by `WriteDOT`: a header line, followed by one
+// statement per line, each declaring a node or an edge. Attributes are
+// ignored.
+func ReadDOT[N comparable](r io.Reader, parse func(string) (N, error)) (*Graph[N], error) {
+	s := bufio.NewScanner(r)
+
+	var g *Graph[N]
+header:
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line != "" {
+			switch {
+			case strings.HasPrefix(line, "digraph"):
+				g = NewDirected[N]()
+			case strings.HasPrefix(line, "graph"):
+				g = NewUndirected[N]()
+			default:
+				return nil, fmt.Errorf("expected DOT graph header, got %q", line)
+			}
+			break header
+		}
+	}
+
+	if g == nil {
+		return nil, fmt.Errorf("empty DOT graph")
+	}
+
+	arrow := "--"
+	if g.directed {
+		arrow = "->"
+	}
+
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line == "}" || strings.HasPrefix(line, "//") {
+			continue
+		}
+
+		src, rest, err := scanDOTID(line)
+		if err != nil {
+			return nil, err
+		}
+
+		a, err := parse(src)
+		if err != nil {
+			return nil, err
+		}
+
+		rest, isEdge := strings.CutPrefix(strings.TrimSpace(rest), arrow)
+		if !isEdge {
+			g.AddNode(a)
+		} else {
+			var dst string
+			if dst, rest, err = scanDOTID(rest); err != nil {
+				return nil, err
+			}
+
+			b, err := parse(dst)
+			if err != nil {
+				return nil, err
+			}
+
+			g.AddEdge(a, b)
+		}
+
+		// Anything left must be attributes, which are ignored.
+		if rest = strings.TrimSpace(rest); rest != "" && rest != ";" && !strings.HasPrefix(rest, "[") {
+			return nil, fmt.Errorf("unexpected %q in DOT statement %q", rest, line)
+		}
+	}
+
+	return g, s.Err()
+}
+
+// Scan a node ID from the start of `line`, returning it and the rest of the
+// line. IDs are either quoted, where `\"` stands for a quote and every other
+// character stands for itself, or run up to the next space, ';' or '['.
+func scanDOTID(line string) (id, rest string, err error) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, `"`) {
+		end := strings.IndexAny(line, " \t;[")
+		if end < 0 {
+			end = len(line)
+		}
+
+		if end == 0 {
+			return "", "", fmt.Errorf("expected DOT node ID, got %q", line)
+		}
+
+		return line[:end], line[end:], nil
+	}
+
+	var b strings.Builder
+	for i := 1; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '"':
+			b.WriteByte('"')
+			i++
+		case line[i] == '"':
+			return b.String(), line[i+1:], nil
+		default:
+			b.WriteByte(line[i])
+		}
+	}
+
+	return "", "", fmt.Errorf("unterminated DOT node ID %s", line)
+}
+
+// Read a graph written by `WriteAdjacency`, ignoring highlights.
+func ReadAdjacency[N comparable](
+	r io.Reader,
+	directed bool,
+	parse func(string) (N, error),
+) (*Graph[N], error) {
+	g := newGraph[N](directed)
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			continue
+		}
+
+		src, dsts, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("expected 'node: successors...', got %q", line)
+		}
+
+		a, err := parse(strings.TrimSuffix(strings.TrimSpace(src), "*"))
+		if err != nil {
+			return nil, err
+		}
+
+		g.AddNode(a)
+		for _, dst := range strings.Fields(dsts) {
+			b, err := parse(strings.TrimSuffix(dst, "*"))
+			if err != nil {
+				return nil, err
+			}
+
+			g.AddEdge(a, b)
+		}
+	}
+
+	return g, s.Err()
+}
+
+// Read a graph written by `WriteEdgeList`. Only the first two columns are
+// used, and the header row is optional.
+func ReadEdgeList[N comparable](
+	r io.Reader,
+	directed bool,
+	parse func(string) (N, error),
+) (*Graph[N], error) {
+	g := newGraph[N](directed)
+
+	c := csv.NewReader(r)
+	c.FieldsPerRecord = -1
+
+	for i := 0; ; i++ {
+		row, err := c.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		if len(row) < 2 {
+			return nil, fmt.Errorf("row %d: expected at least 2 columns, got %d", i+1, len(row))
+		}
+
+		if i == 0 && row[0] == "source" && row[1] == "target" {
+			continue
+		}
+
+		a, err := parse(row[0])
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := parse(row[1])
+		if err != nil {
+			return nil, err
+		}
+
+		g.AddEdge(a, b)
+	}
+
+	return g, nil
+}
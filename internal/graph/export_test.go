@@ -0,0 +1,202 @@
+package graph
+
+import (
+	"bytes"
+	"internal/set"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func parseString(s string) (string, error) {
+	return s, nil
+}
+
+func TestWriteDOT(t *testing.T) {
+	g := NewDirected[int]()
+	g.AddEdge(1, 2)
+	g.AddEdge(2, 3)
+	g.AddEdge(3, 1)
+	g.AddNode(4)
+
+	var b bytes.Buffer
+	if err := g.WriteDOT(&b, Cycle([]int{2, 3})); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := `digraph {
+	"1";
+	"2" [color=red, penwidth=2];
+	"3" [color=red, penwidth=2];
+	"4";
+	"1" -> "2";
+	"2" -> "3" [color=red, penwidth=2];
+	"3" -> "1";
+}
+`
+
+	if actual := b.String(); actual != expect {
+		t.Errorf("expected:\n%s\ngot:\n%s", expect, actual)
+	}
+}
+
+func TestWriteEdgeList(t *testing.T) {
+	g := NewUndirected[string]()
+	g.AddEdge("a", "b")
+	g.AddEdge("c", "b")
+	g.AddEdge("c", "d")
+
+	var b bytes.Buffer
+	if err := g.WriteEdgeList(&b, g.Induced(set.Set[string]{"b": {}, "c": {}})); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := `source,target,highlight
+a,b,false
+b,c,true
+c,d,false
+`
+
+	if actual := b.String(); actual != expect {
+		t.Errorf("expected:\n%s\ngot:\n%s", expect, actual)
+	}
+}
+
+func TestWriteAdjacency(t *testing.T) {
+	g := NewDirected[string]()
+	g.AddEdge("a", "b")
+	g.AddEdge("a", "c")
+	g.AddEdge("c", "b")
+
+	var b bytes.Buffer
+	if err := g.WriteAdjacency(&b, Cycle([]string{"a", "c"})); err != nil {
+		t.Fatal(err)
+	}
+
+	if expect, actual := "a*: b c*\nb:\nc*: b\n", b.String(); actual != expect {
+		t.Errorf("expected:\n%s\ngot:\n%s", expect, actual)
+	}
+}
+
+func TestWriteAdjacencyInvalid(t *testing.T) {
+	for _, name := range []string{"", "a b", "a:b", "a*"} {
+		g := NewDirected[string]()
+		g.AddEdge("x", name)
+
+		var b bytes.Buffer
+		if err := g.WriteAdjacency(&b, Subgraph[string]{}); err == nil {
+			t.Errorf("expected an error for node %q", name)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, directed := range []bool{false, true} {
+		g := newGraph[int](directed)
+		g.AddEdge(47, 53)
+		g.AddEdge(97, 13)
+		g.AddEdge(97, 61)
+		g.AddEdge(61, 53)
+		g.AddNode(100)
+
+		for _, f := range []Format{DOT, ADJACENCY, EDGE_LIST} {
+			var b bytes.Buffer
+			if err := g.Export(&b, f, Subgraph[int]{}); err != nil {
+				t.Fatal(err)
+			}
+
+			h, err := Import(&b, f, directed, strconv.Atoi)
+			if err != nil {
+				t.Fatalf("format %d: %v", f, err)
+			}
+
+			if h.IsDirected() != directed || h.EdgeCount() != g.EdgeCount() {
+				t.Errorf("format %d, directed %v: expected %d edges, got %d (directed %v)",
+					f, directed, g.EdgeCount(), h.EdgeCount(), h.IsDirected())
+			}
+
+			for a, b := range g.Edges() {
+				if !h.HasEdge(a, b) {
+					t.Errorf("format %d, directed %v: missing edge %d -> %d", f, directed, a, b)
+				}
+			}
+
+			// Edge lists have no way to represent isolated nodes.
+			if f != EDGE_LIST && !h.HasNode(100) {
+				t.Errorf("format %d, directed %v: missing isolated node", f, directed)
+			}
+		}
+	}
+}
+
+func TestReadDOTQuoted(t *testing.T) {
+	g, err := ReadDOT(strings.NewReader(`
+graph {
+	"a b" [color=red];
+	"a b" -- "c\"d";
+}
+`), parseString)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g.IsDirected() || !g.HasEdge(`c"d`, "a b") {
+		t.Errorf("expected undirected edge between 'a b' and 'c\"d'")
+	}
+}
+
+func TestRoundTripDOTNames(t *testing.T) {
+	names := []string{"a -> b", "x [y", `q"uote`, `a\"b`, `a\b`, "é", "a;b", "☃"}
+
+	g := NewDirected[string]()
+	for i := 1; i < len(names); i++ {
+		g.AddEdge(names[i-1], names[i])
+	}
+
+	var b bytes.Buffer
+	if err := g.WriteDOT(&b, Cycle(names[:2])); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := ReadDOT(&b, parseString)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if h.EdgeCount() != g.EdgeCount() {
+		t.Errorf("expected %d edges, got %d", g.EdgeCount(), h.EdgeCount())
+	}
+
+	for a, b := range g.Edges() {
+		if !h.HasEdge(a, b) {
+			t.Errorf("missing edge %q -> %q", a, b)
+		}
+	}
+}
+
+func TestWriteDOTInvalid(t *testing.T) {
+	for _, name := range []string{"a\nb", `a\`} {
+		g := NewDirected[string]()
+		g.AddNode(name)
+
+		var b bytes.Buffer
+		if err := g.WriteDOT(&b, Subgraph[string]{}); err == nil {
+			t.Errorf("expected an error for node %q", name)
+		}
+	}
+}
+
+func TestReadDOTUnquoted(t *testing.T) {
+	g, err := ReadDOT(strings.NewReader("digraph {\n\ta -> b [color=red];\n\tc\n}\n"), parseString)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !g.HasEdge("a", "b") || !g.HasNode("c") {
+		t.Errorf("expected edge a -> b and node c")
+	}
+
+	if _, err := ReadDOT(strings.NewReader("digraph {\n\ta -> b c;\n}\n"), parseString); err == nil {
+		t.Errorf("expected an error for trailing text")
+	}
+}
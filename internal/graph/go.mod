@@ -0,0 +1,7 @@
+module graph
+
+go 1.23.1
+
+require internal/set v0.0.0
+
+replace internal/set => ../set
@@ -0,0 +1,242 @@
+package graph
+
+import (
+	"internal/set"
+	"slices"
+)
+
+// A graph whose nodes are identified by values of type `N`. Nodes are
+// remembered in the order they were first added, and all iteration over nodes
+// follows that order, so that results are deterministic.
+type Graph[N comparable] struct {
+	directed bool
+	nodes    []N
+	index    map[N]int
+	out      map[N]set.Set[N]
+	in       map[N]set.Set[N]
+	edges    int
+}
+
+// Summary statistics over the degrees of the nodes in a graph.
+type DegreeStats struct {
+	Min, Max int
+	Mean     float64
+
+	// Number of nodes with each degree.
+	Histogram map[int]int
+}
+
+// Create an empty graph, where edges can be traversed in both directions.
+func NewUndirected[N comparable]() *Graph[N] {
+	return newGraph[N](false)
+}
+
+// Create an empty graph, where edges can only be traversed from their source to
+// their target.
+func NewDirected[N comparable]() *Graph[N] {
+	return newGraph[N](true)
+}
+
+func newGraph[N comparable](directed bool) *Graph[N] {
+	g := &Graph[N]{
+		directed: directed,
+		index:    make(map[N]int),
+		out:      make(map[N]set.Set[N]),
+	}
+
+	if directed {
+		g.in = make(map[N]set.Set[N])
+	} else {
+		g.in = g.out
+	}
+
+	return g
+}
+
+func (g *Graph[N]) IsDirected() bool {
+	return g.directed
+}
+
+// The number of nodes in the graph.
+func (g *Graph[N]) Len() int {
+	return len(g.nodes)
+}
+
+// The number of edges in the graph. In an undirected graph, an edge and its
+// reverse are the same edge.
+func (g *Graph[N]) EdgeCount() int {
+	return g.edges
+}
+
+// Add `n` to the graph, if it is not already there.
+func (g *Graph[N]) AddNode(n N) {
+	if _, ok := g.index[n]; ok {
+		return
+	}
+
+	g.index[n] = len(g.nodes)
+	g.nodes = append(g.nodes, n)
+	g.out[n] = set.New[N]()
+	if g.directed {
+		g.in[n] = set.New[N]()
+	}
+}
+
+// Add an edge from `a` to `b`, adding the nodes themselves if necessary.
+func (g *Graph[N]) AddEdge(a, b N) {
+	g.AddNode(a)
+	g.AddNode(b)
+
+	if g.out[a].Contains(b) {
+		return
+	}
+
+	g.out[a].Add(b)
+	g.in[b].Add(a)
+	g.edges++
+}
+
+func (g *Graph[N]) HasNode(n N) bool {
+	_, ok := g.index[n]
+	return ok
+}
+
+// Check whether there is an edge from `a` to `b`.
+func (g *Graph[N]) HasEdge(a, b N) bool {
+	out, ok := g.out[a]
+	return ok && out.Contains(b)
+}
+
+// Returns the nodes of the graph, in the order they were added.
+func (g *Graph[N]) Nodes() func(yield func(N) bool) {
+	return func(yield func(N) bool) {
+		for _, n := range g.nodes {
+			if !yield(n) {
+				return
+			}
+		}
+	}
+}
+
+// Returns the edges of the graph, ordered by their source and then their
+// target. In an undirected graph each edge is only produced once, from the
+// node that was added first.
+func (g *Graph[N]) Edges() func(yield func(N, N) bool) {
+	return func(yield func(N, N) bool) {
+		for i, a := range g.nodes {
+			for _, b := range g.sorted(g.out[a]) {
+				if !g.directed && g.index[b] < i {
+					continue
+				}
+
+				if !yield(a, b) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// The nodes that `n` has an edge to. The returned set must not be modified.
+func (g *Graph[N]) Successors(n N) set.Set[N] {
+	return g.out[n]
+}
+
+// The nodes that have an edge to `n`. The returned set must not be modified.
+func (g *Graph[N]) Predecessors(n N) set.Set[N] {
+	return g.in[n]
+}
+
+// The number of edges leaving `n`.
+func (g *Graph[N]) OutDegree(n N) int {
+	return g.out[n].Len()
+}
+
+// The number of edges entering `n`.
+func (g *Graph[N]) InDegree(n N) int {
+	return g.in[n].Len()
+}
+
+// The number of nodes adjacent to `n`, ignoring the direction of edges.
+func (g *Graph[N]) Degree(n N) int {
+	return g.adjacent(n).Len()
+}
+
+// Summarise the degrees of all nodes, ignoring the direction of edges.
+func (g *Graph[N]) DegreeStats() (s DegreeStats) {
+	s.Histogram = make(map[int]int)
+	if len(g.nodes) == 0 {
+		return
+	}
+
+	total := 0
+	s.Min = g.Degree(g.nodes[0])
+	for _, n := range g.nodes {
+		d := g.Degree(n)
+		s.Min = min(s.Min, d)
+		s.Max = max(s.Max, d)
+		s.Histogram[d]++
+		total += d
+	}
+
+	s.Mean = float64(total) / float64(len(g.nodes))
+	return
+}
+
+// Returns the connected components of the graph, ignoring the direction of
+// edges. Components are ordered by their first node, and nodes within each
+// component are in the order they were added to the graph.
+func (g *Graph[N]) Components() (components [][]N) {
+	seen := set.New[N]()
+	for _, n := range g.nodes {
+		if seen.Contains(n) {
+			continue
+		}
+
+		seen.Add(n)
+		component := []N{n}
+		for frontier := []N{n}; len(frontier) > 0; {
+			var curr N
+			curr, frontier = frontier[0], frontier[1:]
+			for m := range g.adjacent(curr) {
+				if !seen.Contains(m) {
+					seen.Add(m)
+					component = append(component, m)
+					frontier = append(frontier, m)
+				}
+			}
+		}
+
+		g.order(component)
+		components = append(components, component)
+	}
+
+	return
+}
+
+// The nodes adjacent to `n`, in either direction.
+func (g *Graph[N]) adjacent(n N) set.Set[N] {
+	if !g.directed {
+		return g.out[n]
+	}
+
+	return g.out[n].Union(g.in[n])
+}
+
+// The nodes in `s`, in the order they were added to the graph.
+func (g *Graph[N]) sorted(s set.Set[N]) []N {
+	ns := make([]N, 0, s.Len())
+	for n := range s {
+		ns = append(ns, n)
+	}
+
+	g.order(ns)
+	return ns
+}
+
+// Sort `ns` in place, into the order they were added to the graph.
+func (g *Graph[N]) order(ns []N) {
+	slices.SortFunc(ns, func(a, b N) int {
+		return g.index[a] - g.index[b]
+	})
+}
@@ -0,0 +1,254 @@
+package graph
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// The network from the example in Advent of Code 2024, day 23.
+const example = `kh-tc qp-kh de-cg ka-co yn-aq qp-ub cg-tb vc-aq tb-ka wh-tc yn-cg kh-ub
+ta-co de-co tc-td tb-wq wh-td ta-ka td-qp aq-cg wq-ub ub-vc de-ta wq-aq wq-vc
+wh-yn ka-de kh-ta co-tc wh-qp tb-vc td-yn`
+
+func exampleGraph() *Graph[string] {
+	g := NewUndirected[string]()
+	for _, link := range strings.Fields(example) {
+		ends := strings.SplitN(link, "-", 2)
+		g.AddEdge(ends[0], ends[1])
+	}
+	return g
+}
+
+// Sort the nodes within each group, and then the groups themselves, so that
+// they can be compared regardless of the order they were found in.
+func normalise(groups [][]string) []string {
+	var keys []string
+	for _, group := range groups {
+		group = slices.Clone(group)
+		slices.Sort(group)
+		keys = append(keys, strings.Join(group, ","))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestUndirected(t *testing.T) {
+	g := NewUndirected[int]()
+	g.AddEdge(1, 2)
+	g.AddEdge(2, 1)
+	g.AddEdge(2, 3)
+	g.AddNode(4)
+
+	if g.Len() != 4 || g.EdgeCount() != 2 {
+		t.Errorf("expected 4 nodes and 2 edges, got %d and %d", g.Len(), g.EdgeCount())
+	}
+
+	if !g.HasEdge(1, 2) || !g.HasEdge(2, 1) || g.HasEdge(1, 3) {
+		t.Errorf("unexpected adjacency")
+	}
+
+	var edges [][2]int
+	for a, b := range g.Edges() {
+		edges = append(edges, [2]int{a, b})
+	}
+
+	if !slices.Equal(edges, [][2]int{{1, 2}, {2, 3}}) {
+		t.Errorf("expected edges [[1 2] [2 3]], got %v", edges)
+	}
+
+	if g.Degree(2) != 2 || g.Degree(4) != 0 {
+		t.Errorf("expected degrees 2 and 0, got %d and %d", g.Degree(2), g.Degree(4))
+	}
+}
+
+func TestDirected(t *testing.T) {
+	g := NewDirected[int]()
+	g.AddEdge(1, 2)
+	g.AddEdge(2, 1)
+	g.AddEdge(3, 2)
+
+	if g.EdgeCount() != 3 {
+		t.Errorf("expected 3 edges, got %d", g.EdgeCount())
+	}
+
+	if !g.HasEdge(3, 2) || g.HasEdge(2, 3) {
+		t.Errorf("expected edge 3 -> 2 but not 2 -> 3")
+	}
+
+	if g.OutDegree(2) != 1 || g.InDegree(2) != 2 || g.Degree(2) != 2 {
+		t.Errorf("expected node 2 to have out-degree 1, in-degree 2 and degree 2")
+	}
+}
+
+func TestComponents(t *testing.T) {
+	g := NewDirected[int]()
+	g.AddEdge(1, 2)
+	g.AddEdge(3, 2)
+	g.AddEdge(5, 4)
+	g.AddNode(6)
+
+	actual := g.Components()
+	expect := [][]int{{1, 2, 3}, {5, 4}, {6}}
+	if !slices.EqualFunc(actual, expect, slices.Equal) {
+		t.Errorf("expected %v, got %v", expect, actual)
+	}
+}
+
+func TestDegreeStats(t *testing.T) {
+	s := exampleGraph().DegreeStats()
+	if s.Min != 4 || s.Max != 4 || s.Mean != 4 || s.Histogram[4] != 16 {
+		t.Errorf("expected every node to have degree 4, got %+v", s)
+	}
+}
+
+func TestTriangles(t *testing.T) {
+	g := exampleGraph()
+
+	var triangles [][]string
+	for tri := range g.Triangles() {
+		triangles = append(triangles, tri[:])
+	}
+
+	if len(triangles) != 12 {
+		t.Errorf("expected 12 triangles, got %d", len(triangles))
+	}
+
+	var triples [][]string
+	for c := range g.KCliques(3) {
+		triples = append(triples, c)
+	}
+
+	if expect, actual := normalise(triangles), normalise(triples); !slices.Equal(expect, actual) {
+		t.Errorf("expected 3-cliques to match triangles:\n%v\n%v", expect, actual)
+	}
+}
+
+func TestKCliques(t *testing.T) {
+	g := NewUndirected[int]()
+	for i := 0; i < 5; i++ {
+		for j := i + 1; j < 5; j++ {
+			g.AddEdge(i, j)
+		}
+	}
+
+	// A complete graph on 5 nodes has (5 choose k) cliques of size k.
+	for k, expect := range []int{0, 5, 10, 10, 5, 1, 0} {
+		count := 0
+		for range g.KCliques(k) {
+			count++
+		}
+
+		if count != expect {
+			t.Errorf("expected %d %d-cliques, got %d", expect, k, count)
+		}
+	}
+}
+
+func TestMaximalCliques(t *testing.T) {
+	g := exampleGraph()
+	g.AddNode("zz")
+
+	var cliques [][]string
+	largest := 0
+	for _, c := range g.MaximalCliques() {
+		var nodes []string
+		for n := range c {
+			nodes = append(nodes, n)
+		}
+		cliques = append(cliques, nodes)
+		largest = max(largest, len(nodes))
+	}
+
+	// No clique is reported twice, or contained in another.
+	keys := normalise(cliques)
+	for i, a := range keys {
+		for j, b := range keys {
+			if i != j && isSubset(strings.Split(a, ","), strings.Split(b, ",")) {
+				t.Errorf("clique %s is contained in %s", a, b)
+			}
+		}
+	}
+
+	if !slices.Contains(keys, "zz") {
+		t.Errorf("expected isolated node to be its own maximal clique")
+	}
+
+	var maximum []string
+	for n := range g.MaximumClique() {
+		maximum = append(maximum, n)
+	}
+
+	if expect, actual := "co,de,ka,ta", normalise([][]string{maximum})[0]; expect != actual {
+		t.Errorf("expected maximum clique %s, got %s", expect, actual)
+	}
+}
+
+func TestMaximalCliquesDeterministic(t *testing.T) {
+	r := rand.New(rand.NewSource(35))
+	for i := 0; i < 50; i++ {
+		g := NewUndirected[int]()
+		for a := range 20 {
+			g.AddNode(a)
+			for b := range a {
+				if r.Float64() < 0.4 {
+					g.AddEdge(a, b)
+				}
+			}
+		}
+
+		found := func() (cliques []string) {
+			for _, c := range g.MaximalCliques() {
+				cliques = append(cliques, fmt.Sprint(g.sorted(c)))
+			}
+			return
+		}
+
+		expect := found()
+		for j := 0; j < 5; j++ {
+			if actual := found(); !slices.Equal(expect, actual) {
+				t.Fatalf("expected cliques in the same order, got %v then %v", expect, actual)
+			}
+		}
+	}
+}
+
+func TestDegeneracyOrder(t *testing.T) {
+	// A star and a separate edge: a forest, so every node should have at most
+	// one neighbour later in the order.
+	g := NewUndirected[int]()
+	for i := 1; i <= 4; i++ {
+		g.AddEdge(0, i)
+	}
+	g.AddEdge(5, 6)
+
+	order := g.DegeneracyOrder()
+	if len(order) != g.Len() {
+		t.Fatalf("expected %d nodes in order, got %v", g.Len(), order)
+	}
+
+	for i, n := range order {
+		later := 0
+		for _, m := range order[i+1:] {
+			if g.HasEdge(n, m) {
+				later++
+			}
+		}
+
+		if later > 1 {
+			t.Errorf("node %d has %d later neighbours in %v", n, later, order)
+		}
+	}
+}
+
+func isSubset(a, b []string) bool {
+	for _, n := range a {
+		if !slices.Contains(b, n) {
+			return false
+		}
+	}
+	return true
+}
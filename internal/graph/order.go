@@ -0,0 +1,148 @@
+package graph
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Find a cycle in the graph, returning its nodes in the order they are
+// visited, and whether one was found. In an undirected graph, only cycles of
+// three or more nodes count, as every edge can otherwise be traversed there
+// and back again.
+func (g *Graph[N]) FindCycle() ([]N, bool) {
+	const (
+		UNVISITED = iota
+		ACTIVE
+		DONE
+	)
+
+	state := make(map[N]int, len(g.nodes))
+	parent := make(map[N]N, len(g.nodes))
+
+	// Depth-first search, where nodes are ACTIVE while they are on the search
+	// stack. Reaching an ACTIVE node again closes a cycle, which can be read
+	// off by following parents back from where the search currently is.
+	var cycle []N
+	var visit func(n N) bool
+	visit = func(n N) bool {
+		state[n] = ACTIVE
+		for _, m := range g.sorted(g.out[n]) {
+			if p, ok := parent[n]; ok && !g.directed && p == m {
+				continue
+			}
+
+			switch state[m] {
+			case UNVISITED:
+				parent[m] = n
+				if visit(m) {
+					return true
+				}
+			case ACTIVE:
+				for c := n; c != m; c = parent[c] {
+					cycle = append(cycle, c)
+				}
+				cycle = append(cycle, m)
+
+				slices.Reverse(cycle)
+				return true
+			}
+		}
+
+		state[n] = DONE
+		return false
+	}
+
+	for _, n := range g.nodes {
+		if state[n] == UNVISITED && visit(n) {
+			return cycle, true
+		}
+	}
+
+	return nil, false
+}
+
+// Returned when a graph cannot be ordered topologically because it contains a
+// cycle.
+type CycleError[N comparable] struct {
+	Cycle []N
+}
+
+// Order the nodes of a directed graph so that every edge goes from an earlier
+// node to a later one. Where there is a choice of which node to place next,
+// the one added to the graph first is chosen. Also returns whether this is the
+// only valid order.
+//
+// Fails with a `*CycleError` if there is no valid order.
+func (g *Graph[N]) TopologicalSort() (order []N, unique bool, err error) {
+	// Kahn's algorithm: repeatedly place a node with no incoming edges from
+	// nodes that have not been placed yet. The order is unique exactly when
+	// there is never more than one such node to choose from.
+	remaining := make(map[N]int, len(g.nodes))
+	var ready []N
+	for _, n := range g.nodes {
+		if remaining[n] = g.in[n].Len(); remaining[n] == 0 {
+			ready = append(ready, n)
+		}
+	}
+
+	unique = true
+	for len(ready) > 0 {
+		if len(ready) > 1 {
+			unique = false
+		}
+
+		// Keep `ready` sorted by insertion order, so that ties break
+		// consistently.
+		g.order(ready)
+
+		var n N
+		n, ready = ready[0], ready[1:]
+		order = append(order, n)
+
+		for m := range g.out[n] {
+			if remaining[m]--; remaining[m] == 0 {
+				ready = append(ready, m)
+			}
+		}
+	}
+
+	if len(order) < len(g.nodes) {
+		cycle, _ := g.FindCycle()
+		return nil, false, &CycleError[N]{cycle}
+	}
+
+	return order, unique, nil
+}
+
+// The graph containing only `nodes` (in the order given), and the edges
+// between them.
+func (g *Graph[N]) Restrict(nodes []N) *Graph[N] {
+	h := newGraph[N](g.directed)
+	for _, n := range nodes {
+		h.AddNode(n)
+	}
+
+	for _, a := range nodes {
+		for _, b := range g.sorted(g.out[a]) {
+			if h.HasNode(b) {
+				h.AddEdge(a, b)
+			}
+		}
+	}
+
+	return h
+}
+
+func (e *CycleError[N]) Error() string {
+	nodes := make([]string, 0, len(e.Cycle)+1)
+	for _, n := range e.Cycle {
+		nodes = append(nodes, fmt.Sprint(n))
+	}
+
+	if len(e.Cycle) > 0 {
+		nodes = append(nodes, fmt.Sprint(e.Cycle[0]))
+	}
+
+	return "cycle: " + strings.Join(nodes, " -> ")
+}
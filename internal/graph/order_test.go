@@ -0,0 +1,91 @@
+package graph
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestFindCycle(t *testing.T) {
+	g := NewDirected[int]()
+	g.AddEdge(1, 2)
+	g.AddEdge(2, 3)
+	g.AddEdge(1, 3)
+
+	if c, ok := g.FindCycle(); ok {
+		t.Errorf("expected no cycle, got %v", c)
+	}
+
+	g.AddEdge(3, 4)
+	g.AddEdge(4, 2)
+	c, ok := g.FindCycle()
+	if !ok || len(c) != 3 {
+		t.Fatalf("expected a cycle of 3, got %v", c)
+	}
+
+	for i, n := range c {
+		if m := c[(i+1)%len(c)]; !g.HasEdge(n, m) {
+			t.Errorf("cycle %v has no edge %d -> %d", c, n, m)
+		}
+	}
+
+	u := NewUndirected[int]()
+	u.AddEdge(0, 1)
+	u.AddEdge(1, 2)
+	if c, ok := u.FindCycle(); ok {
+		t.Errorf("expected no cycle in a path, got %v", c)
+	}
+
+	u.AddEdge(2, 0)
+	if c, ok := u.FindCycle(); !ok || len(c) != 3 {
+		t.Errorf("expected a cycle of 3, got %v", c)
+	}
+}
+
+func TestTopologicalSort(t *testing.T) {
+	g := NewDirected[int]()
+	g.AddEdge(1, 2)
+	g.AddEdge(2, 3)
+	g.AddEdge(1, 3)
+
+	order, unique, err := g.TopologicalSort()
+	if err != nil || !unique || !slices.Equal(order, []int{1, 2, 3}) {
+		t.Errorf("expected unique order [1 2 3], got %v (unique: %v, err: %v)", order, unique, err)
+	}
+
+	// 4 could go anywhere, so the order is no longer unique, and ties go to the
+	// node added first.
+	g.AddNode(4)
+	order, unique, err = g.TopologicalSort()
+	if err != nil || unique || !slices.Equal(order, []int{1, 2, 3, 4}) {
+		t.Errorf("expected ambiguous order [1 2 3 4], got %v (unique: %v, err: %v)", order, unique, err)
+	}
+
+	g.AddEdge(3, 1)
+	_, _, err = g.TopologicalSort()
+
+	var cycle *CycleError[int]
+	if !errors.As(err, &cycle) || len(cycle.Cycle) == 0 {
+		t.Fatalf("expected a cycle error, got %v", err)
+	}
+
+	if err.Error() != "cycle: 1 -> 2 -> 3 -> 1" {
+		t.Errorf("unexpected error message: %v", err)
+	}
+}
+
+func TestRestrict(t *testing.T) {
+	g := NewDirected[int]()
+	g.AddEdge(1, 2)
+	g.AddEdge(2, 3)
+	g.AddEdge(3, 1)
+
+	h := g.Restrict([]int{3, 1})
+	if h.Len() != 2 || h.EdgeCount() != 1 || !h.HasEdge(3, 1) {
+		t.Errorf("expected only the edge 3 -> 1 to remain")
+	}
+
+	if order, _, err := h.TopologicalSort(); err != nil || !slices.Equal(order, []int{3, 1}) {
+		t.Errorf("expected order [3 1], got %v (err: %v)", order, err)
+	}
+}
@@ -0,0 +1,3 @@
+module linalg
+
+go 1.23.1
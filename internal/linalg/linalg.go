@@ -0,0 +1,293 @@
+package linalg
+
+import "math/big"
+
+// The integer solutions to a system of linear equations. Every solution is
+// `Origin` plus an integer combination of the vectors in `Kernel`, and every
+// such vector is a solution. The kernel vectors are linearly independent, so
+// each solution is reached by exactly one combination of them.
+type Lattice struct {
+	Origin []*big.Int
+	Kernel [][]*big.Int
+}
+
+// Find all integer solutions `x` to `a x = b`, where `a` has a row per
+// equation and a column per unknown. Returns false if there are none, which
+// includes systems that only have solutions over the rationals.
+//
+// `a` is reduced to lower echelon form (its column-style Hermite normal form,
+// without normalising entries), `h = a u`, by unimodular column operations.
+// Each operation combines two columns using the coefficients from the extended
+// Euclidean algorithm, to zero an entry in one of them, so that systems whose
+// columns are linearly dependent are handled the same way as any other.
+// Solutions to `h y = b` are found by forward substitution and mapped back
+// through `x = u y`, and the columns of `u` corresponding to columns of `h`
+// that were zeroed span the kernel. Entries can grow during reduction, so all
+// arithmetic is done on `big.Int`s, and the kernel vectors that come out of it
+// can be much longer than they need to be, so they are LLL-reduced (see
+// `reduce`) to keep them short.
+func Solve(a [][]int, b []int) (Lattice, bool) {
+	rows, cols := len(a), 0
+	if rows > 0 {
+		cols = len(a[0])
+	}
+
+	h := make([][]*big.Int, rows)
+	for i, row := range a {
+		if len(row) != cols {
+			panic("ragged matrix")
+		}
+		h[i] = ints(row)
+	}
+
+	u := identity(cols)
+
+	// The column holding each row's pivot, or -1 if the row has no pivot,
+	// because it is a combination of earlier rows.
+	pivots := make([]int, rows)
+
+	c := 0
+	for i := range h {
+		pivots[i] = -1
+		if c == cols {
+			continue
+		}
+
+		for j := c + 1; j < cols; j++ {
+			if h[i][j].Sign() == 0 {
+				continue
+			}
+
+			if h[i][c].Sign() == 0 {
+				swap(h, c, j)
+				swap(u, c, j)
+				continue
+			}
+
+			// With `g = s p + t q`, replacing columns `c` and `j` by `s c + t j`
+			// and `-q/g c + p/g j` puts `g` in column `c` and zero in column `j`
+			// on this row, and the transformation has determinant one.
+			var g, s, t, p, q big.Int
+			g.GCD(&s, &t, h[i][c], h[i][j])
+			p.Quo(h[i][c], &g)
+			q.Quo(h[i][j], &g)
+			q.Neg(&q)
+
+			combine(h, c, j, &s, &t, &q, &p)
+			combine(u, c, j, &s, &t, &q, &p)
+		}
+
+		if h[i][c].Sign() != 0 {
+			pivots[i] = c
+			c++
+		}
+	}
+
+	y := make([]*big.Int, cols)
+	for j := range y {
+		y[j] = new(big.Int)
+	}
+
+	for i, row := range h {
+		var r, m big.Int
+		r.SetInt64(int64(b[i]))
+		for j, e := range row {
+			if j != pivots[i] {
+				r.Sub(&r, m.Mul(e, y[j]))
+			}
+		}
+
+		if pivots[i] < 0 {
+			if r.Sign() != 0 {
+				return Lattice{}, false
+			}
+			continue
+		}
+
+		if y[pivots[i]].QuoRem(&r, row[pivots[i]], &m); m.Sign() != 0 {
+			return Lattice{}, false
+		}
+	}
+
+	l := Lattice{Origin: make([]*big.Int, cols)}
+	for i := range l.Origin {
+		l.Origin[i] = new(big.Int)
+		for j := range y {
+			var m big.Int
+			l.Origin[i].Add(l.Origin[i], m.Mul(u[i][j], y[j]))
+		}
+	}
+
+	for j := c; j < cols; j++ {
+		k := make([]*big.Int, cols)
+		for i := range k {
+			k[i] = u[i][j]
+		}
+		l.Kernel = append(l.Kernel, k)
+	}
+
+	reduce(l.Kernel)
+	return l, true
+}
+
+// LLL-reduce the lattice basis `b` in place, with δ = 3/4, so that its vectors
+// are short and close to orthogonal. Every step adds an integer multiple of
+// one vector to another or swaps two vectors, so the lattice doesn't change.
+//
+// `mu` holds the Gram-Schmidt coefficients of the basis, and `norms` the
+// squared lengths of its orthogonalised vectors. They are recomputed from
+// scratch after each swap, which is slower than updating them, but the bases
+// being reduced are tiny.
+func reduce(b [][]*big.Int) {
+	delta := big.NewRat(3, 4)
+
+	mu, norms := gramSchmidt(b)
+	for k := 1; k < len(b); {
+		for j := k - 1; j >= 0; j-- {
+			q := round(mu[k][j])
+			if q.Sign() == 0 {
+				continue
+			}
+
+			// b[k] -= q b[j], which changes the coefficients of b[k] on b[j] and
+			// on everything before it.
+			for i := range b[k] {
+				var m big.Int
+				b[k][i] = new(big.Int).Sub(b[k][i], m.Mul(q, b[j][i]))
+			}
+
+			r := new(big.Rat).SetInt(q)
+			for i := 0; i <= j; i++ {
+				m := new(big.Rat).Set(r)
+				if i < j {
+					m.Mul(m, mu[j][i])
+				}
+				mu[k][i] = new(big.Rat).Sub(mu[k][i], m)
+			}
+		}
+
+		// Lovász condition: |b*[k]|² >= (δ - mu[k][k-1]²) |b*[k-1]|²
+		var lhs big.Rat
+		lhs.Mul(mu[k][k-1], mu[k][k-1])
+		lhs.Sub(delta, &lhs)
+		lhs.Mul(&lhs, norms[k-1])
+
+		if norms[k].Cmp(&lhs) >= 0 {
+			k++
+			continue
+		}
+
+		b[k], b[k-1] = b[k-1], b[k]
+		mu, norms = gramSchmidt(b)
+		k = max(k-1, 1)
+	}
+}
+
+// The Gram-Schmidt coefficients of the linearly independent vectors `b`, where
+// `mu[i][j]` is the coefficient of `b[i]` on the j-th orthogonalised vector,
+// for `j < i`, and the squared lengths of the orthogonalised vectors.
+func gramSchmidt(b [][]*big.Int) (mu [][]*big.Rat, norms []*big.Rat) {
+	ortho := make([][]*big.Rat, len(b))
+	mu = make([][]*big.Rat, len(b))
+	norms = make([]*big.Rat, len(b))
+
+	for i, v := range b {
+		ortho[i] = make([]*big.Rat, len(v))
+		for c, e := range v {
+			ortho[i][c] = new(big.Rat).SetInt(e)
+		}
+
+		mu[i] = make([]*big.Rat, i)
+		for j := 0; j < i; j++ {
+			// <b[i], b*[j]> / |b*[j]|²
+			m := new(big.Rat)
+			for c, e := range v {
+				var p big.Rat
+				m.Add(m, p.Mul(new(big.Rat).SetInt(e), ortho[j][c]))
+			}
+
+			mu[i][j] = m.Quo(m, norms[j])
+			for c := range ortho[i] {
+				var p big.Rat
+				ortho[i][c].Sub(ortho[i][c], p.Mul(mu[i][j], ortho[j][c]))
+			}
+		}
+
+		norms[i] = new(big.Rat)
+		for _, e := range ortho[i] {
+			var p big.Rat
+			norms[i].Add(norms[i], p.Mul(e, e))
+		}
+	}
+
+	return
+}
+
+// The integer nearest to `r`, rounding halves up.
+func round(r *big.Rat) *big.Int {
+	// floor((2n + d) / 2d), where division rounds towards negative infinity
+	// because the denominator is positive.
+	var n, d big.Int
+	n.Lsh(r.Num(), 1)
+	n.Add(&n, r.Denom())
+	d.Lsh(r.Denom(), 1)
+	return n.Div(&n, &d)
+}
+
+// The solution at integer coordinates `t` in the lattice's kernel basis.
+func (l Lattice) At(t ...int) []*big.Int {
+	if len(t) != len(l.Kernel) {
+		panic("wrong number of coordinates")
+	}
+
+	x := make([]*big.Int, len(l.Origin))
+	for i, o := range l.Origin {
+		x[i] = new(big.Int).Set(o)
+		for j, k := range l.Kernel {
+			var m big.Int
+			x[i].Add(x[i], m.Mul(big.NewInt(int64(t[j])), k[i]))
+		}
+	}
+
+	return x
+}
+
+func ints(xs []int) []*big.Int {
+	out := make([]*big.Int, len(xs))
+	for i, x := range xs {
+		out[i] = big.NewInt(int64(x))
+	}
+	return out
+}
+
+func identity(n int) [][]*big.Int {
+	m := make([][]*big.Int, n)
+	for i := range m {
+		m[i] = make([]*big.Int, n)
+		for j := range m[i] {
+			m[i][j] = new(big.Int)
+		}
+		m[i][i].SetInt64(1)
+	}
+	return m
+}
+
+// Swap columns `c` and `j` of `m`.
+func swap(m [][]*big.Int, c, j int) {
+	for _, row := range m {
+		row[c], row[j] = row[j], row[c]
+	}
+}
+
+// Replace columns `c` and `j` of `m` with `s c + t j` and `x c + y j`.
+func combine(m [][]*big.Int, c, j int, s, t, x, y *big.Int) {
+	for _, row := range m {
+		var sc, tj, xc, yj big.Int
+		sc.Mul(s, row[c])
+		tj.Mul(t, row[j])
+		xc.Mul(x, row[c])
+		yj.Mul(y, row[j])
+		row[c] = sc.Add(&sc, &tj)
+		row[j] = xc.Add(&xc, &yj)
+	}
+}
@@ -0,0 +1,357 @@
+package linalg
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// Check that `x` solves `a x = b`.
+func satisfies(a [][]int, b []int, x []*big.Int) bool {
+	for i, row := range a {
+		var total big.Int
+		for j, e := range row {
+			var m big.Int
+			total.Add(&total, m.Mul(big.NewInt(int64(e)), x[j]))
+		}
+
+		if total.Cmp(big.NewInt(int64(b[i]))) != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// The cheapest non-negative solution to `a x = b`, found by trying every `x`
+// with components up to `limit`.
+func bruteForce(a [][]int, b, cost []int, limit int) (best int, ok bool) {
+	x := make([]int, len(cost))
+	for {
+		total := 0
+		for j := range x {
+			total += cost[j] * x[j]
+		}
+
+		if !ok || total < best {
+			solves := true
+			for i, row := range a {
+				sum := 0
+				for j, e := range row {
+					sum += e * x[j]
+				}
+				solves = solves && sum == b[i]
+			}
+
+			if solves {
+				best, ok = total, true
+			}
+		}
+
+		j := 0
+		for ; j < len(x) && x[j] == limit; j++ {
+			x[j] = 0
+		}
+
+		if j == len(x) {
+			return
+		}
+		x[j]++
+	}
+}
+
+func TestSolveUnique(t *testing.T) {
+	a := [][]int{{94, 22}, {34, 67}}
+	l, ok := Solve(a, []int{8400, 5400})
+	if !ok {
+		t.Fatalf("expected a solution")
+	}
+
+	if len(l.Kernel) != 0 {
+		t.Errorf("expected a unique solution, got kernel %v", l.Kernel)
+	}
+
+	if fmt.Sprint(l.Origin) != "[80 40]" {
+		t.Errorf("expected [80 40], got %v", l.Origin)
+	}
+}
+
+func TestSolveRationalOnly(t *testing.T) {
+	if l, ok := Solve([][]int{{26, 67}, {66, 21}}, []int{12748, 12176}); ok {
+		t.Errorf("expected no integer solution, got %v", l)
+	}
+}
+
+func TestSolveInconsistent(t *testing.T) {
+	// Both buttons move diagonally, but the prize is off the diagonal.
+	if l, ok := Solve([][]int{{1, 3}, {1, 3}}, []int{10, 11}); ok {
+		t.Errorf("expected no solution, got %v", l)
+	}
+}
+
+func TestSolveSingular(t *testing.T) {
+	a, b := [][]int{{2, 6}, {4, 12}}, []int{20, 40}
+	l, ok := Solve(a, b)
+	if !ok {
+		t.Fatalf("expected a solution")
+	}
+
+	if len(l.Kernel) != 1 {
+		t.Fatalf("expected a line of solutions, got kernel %v", l.Kernel)
+	}
+
+	for s := -5; s <= 5; s++ {
+		if x := l.At(s); !satisfies(a, b, x) {
+			t.Errorf("expected %v to be a solution", x)
+		}
+	}
+
+	// The kernel is primitive: Stepping along it reaches every solution,
+	// including these neighbouring ones.
+	for _, x := range [][]int{{1, 3}, {4, 2}, {7, 1}, {10, 0}} {
+		found := false
+		for s := -20; s <= 20 && !found; s++ {
+			found = fmt.Sprint(l.At(s)) == fmt.Sprint(x)
+		}
+
+		if !found {
+			t.Errorf("expected %v in the lattice", x)
+		}
+	}
+}
+
+func TestSolveDivisibility(t *testing.T) {
+	// 4x + 6y is always even.
+	if _, ok := Solve([][]int{{4, 6}}, []int{7}); ok {
+		t.Errorf("expected no solution")
+	}
+
+	l, ok := Solve([][]int{{4, 6}}, []int{8})
+	if !ok || !satisfies([][]int{{4, 6}}, []int{8}, l.Origin) {
+		t.Errorf("expected a solution, got %v", l)
+	}
+}
+
+func TestMinCostLine(t *testing.T) {
+	l, ok := Solve([][]int{{1, 3}, {1, 3}}, []int{10, 10})
+	if !ok {
+		t.Fatalf("expected a solution")
+	}
+
+	for _, tc := range []struct {
+		cost   []int
+		expect string
+		total  int64
+	}{
+		{[]int{3, 1}, "[1 3]", 6},
+		{[]int{1, 5}, "[10 0]", 10},
+	} {
+		x, total, ok := l.MinCost(tc.cost)
+		if !ok || fmt.Sprint(x) != tc.expect || total.Int64() != tc.total {
+			t.Errorf("cost %v: expected %s (%d), got %v (%v)", tc.cost, tc.expect, tc.total, x, total)
+		}
+	}
+}
+
+func TestMinCostInfeasible(t *testing.T) {
+	// The only solutions need a negative number of presses of one button.
+	l, ok := Solve([][]int{{1, 2}, {3, 1}}, []int{0, 5})
+	if !ok {
+		t.Fatalf("expected a solution")
+	}
+
+	if x, _, ok := l.MinCost([]int{1, 1}); ok {
+		t.Errorf("expected no non-negative solution, got %v", x)
+	}
+}
+
+func TestMinCostLarge(t *testing.T) {
+	// Much further away than the prizes in the puzzle.
+	const far = 10_000_000_000_000_000
+	a, b := [][]int{{3, 6}, {5, 10}}, []int{3 * far, 5 * far}
+
+	l, ok := Solve(a, b)
+	if !ok {
+		t.Fatalf("expected a solution")
+	}
+
+	x, total, ok := l.MinCost([]int{3, 1})
+	if !ok || fmt.Sprint(x) != fmt.Sprint([]int{0, far / 2}) || total.Int64() != far/2 {
+		t.Errorf("expected [0 %d], got %v (%v)", far/2, x, total)
+	}
+}
+
+func TestMinCostMatchesBruteForce(t *testing.T) {
+	r := rand.New(rand.NewSource(13))
+	for i := 0; i < 300; i++ {
+		rows, cols := 1+r.Intn(2), 1+r.Intn(3)
+		a := make([][]int, rows)
+		for i := range a {
+			a[i] = make([]int, cols)
+			for j := range a[i] {
+				a[i][j] = 1 + r.Intn(5)
+			}
+		}
+
+		b := make([]int, rows)
+		for i := range b {
+			b[i] = r.Intn(41)
+		}
+
+		cost := make([]int, cols)
+		for j := range cost {
+			cost[j] = r.Intn(5)
+		}
+
+		expect, feasible := bruteForce(a, b, cost, 40)
+
+		l, ok := Solve(a, b)
+		if !ok {
+			if feasible {
+				t.Fatalf("%v x = %v: expected a solution", a, b)
+			}
+			continue
+		}
+
+		x, total, ok := l.MinCost(cost)
+		if ok != feasible {
+			t.Fatalf("%v x = %v: expected feasible = %v, got %v", a, b, feasible, ok)
+		}
+
+		if !ok {
+			continue
+		}
+
+		if !satisfies(a, b, x) || total.Int64() != int64(expect) {
+			t.Fatalf("%v x = %v, cost %v: expected cost %d, got %v (%v)", a, b, cost, expect, x, total)
+		}
+	}
+}
+
+func TestMinCostHigherDimensions(t *testing.T) {
+	// Kernels with two or three dimensions, which need to be searched.
+	r := rand.New(rand.NewSource(46))
+	for i := 0; i < 100; i++ {
+		rows, cols := 1+r.Intn(2), 4
+		a := make([][]int, rows)
+		for i := range a {
+			a[i] = make([]int, cols)
+			for j := range a[i] {
+				a[i][j] = 1 + r.Intn(4)
+			}
+		}
+
+		b := make([]int, rows)
+		for i := range b {
+			b[i] = r.Intn(16)
+		}
+
+		cost := make([]int, cols)
+		for j := range cost {
+			cost[j] = r.Intn(5)
+		}
+
+		expect, feasible := bruteForce(a, b, cost, 15)
+
+		l, ok := Solve(a, b)
+		if !ok {
+			if feasible {
+				t.Fatalf("%v x = %v: expected a solution", a, b)
+			}
+			continue
+		}
+
+		x, total, ok := l.MinCost(cost)
+		if ok != feasible {
+			t.Fatalf("%v x = %v: expected feasible = %v, got %v", a, b, feasible, ok)
+		}
+
+		if ok && (!satisfies(a, b, x) || total.Int64() != int64(expect)) {
+			t.Fatalf("%v x = %v, cost %v: expected cost %d, got %v (%v)", a, b, cost, expect, x, total)
+		}
+	}
+}
+
+func TestMinCostOneSided(t *testing.T) {
+	// Pressing the first and last buttons together goes nowhere, so there are
+	// solutions with as many presses as you like.
+	a, b, cost := [][]int{{1, 3, -1}}, []int{20}, []int{2, 3, 0}
+	l, ok := Solve(a, b)
+	if !ok {
+		t.Fatalf("expected a solution")
+	}
+
+	x, total, ok := l.MinCost(cost)
+	if expect, _ := bruteForce(a, b, cost, 30); !ok || !satisfies(a, b, x) || total.Int64() != int64(expect) {
+		t.Errorf("expected cost %d, got %v (%v)", expect, x, total)
+	}
+}
+
+func TestMinCostFreeRay(t *testing.T) {
+	// The last two buttons cancel out and are free, so there is no telling
+	// how many times they have been pressed.
+	l, ok := Solve([][]int{{1, 2, 1, -1}}, []int{7})
+	if !ok {
+		t.Fatalf("expected a solution")
+	}
+
+	if x, _, ok := l.MinCost([]int{3, 1, 0, 0}); ok {
+		t.Errorf("expected no answer, got %v", x)
+	}
+}
+
+func TestMinCostFar(t *testing.T) {
+	a := [][]int{{90, 21, 76, 84}, {47, 53, 10, 38}}
+	b := []int{10_000_000_005_719, 10_000_000_015_104}
+
+	l, ok := Solve(a, b)
+	if !ok {
+		t.Fatalf("expected a solution")
+	}
+
+	x, total, ok := l.MinCost([]int{3, 1, 4, 4})
+	if !ok || !satisfies(a, b, x) {
+		t.Fatalf("expected a solution, got %v", x)
+	}
+
+	// No nearby solution is cheaper.
+	for s := -20; s <= 20; s++ {
+		for u := -20; u <= 20; u++ {
+			y := Lattice{x, l.Kernel}.At(s, u)
+
+			var c big.Int
+			feasible := true
+			for i, e := range y {
+				var m big.Int
+				c.Add(&c, m.Mul(e, big.NewInt(int64([]int{3, 1, 4, 4}[i]))))
+				feasible = feasible && e.Sign() >= 0
+			}
+
+			if feasible && c.Cmp(total) < 0 {
+				t.Fatalf("expected cost %v to be cheapest, but %v costs %v", total, y, &c)
+			}
+		}
+	}
+}
+
+func BenchmarkMinCost(b *testing.B) {
+	for _, bm := range []struct {
+		name  string
+		a     [][]int
+		prize []int
+	}{
+		{"two buttons", [][]int{{94, 22}, {34, 67}}, []int{10_000_000_008_400, 10_000_000_005_400}},
+		{"four buttons", [][]int{{90, 21, 76, 84}, {47, 53, 10, 38}}, []int{10_000_000_005_719, 10_000_000_015_104}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			cost := []int{3, 1, 4, 4}[:len(bm.a[0])]
+			for i := 0; i < b.N; i++ {
+				l, ok := Solve(bm.a, bm.prize)
+				if ok {
+					l.MinCost(cost)
+				}
+			}
+		})
+	}
+}
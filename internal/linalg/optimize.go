@@ -0,0 +1,440 @@
+package linalg
+
+import "math/big"
+
+// A constraint on coordinates `t` in a lattice's kernel basis, of the form
+// `coeffs · t + constant >= 0`.
+type constraint struct {
+	coeffs   []*big.Int
+	constant *big.Int
+}
+
+var BIG_1 = big.NewInt(1)
+
+// Find the solution in the lattice whose components are all non-negative, and
+// that minimises `cost · x`, along with that cost. Costs must be non-negative.
+// Returns false if no solution is non-negative. When there are several
+// cheapest solutions, any one of them may be returned.
+//
+// Each component of `x` being non-negative constrains the solution's
+// coordinates in the kernel basis. The range of each coordinate is found by
+// eliminating the others from these constraints (Fourier-Motzkin elimination),
+// which gives the range over the rationals. When there is only one coordinate,
+// the cost is linear in it, so the cheapest solution is at one end of its
+// range. Otherwise, the search branches on the coordinate with the narrowest
+// range, and bounds the cost of each branch by eliminating the other
+// coordinates from the constraints along with the cost (see `costBound`).
+// Branches are tried outwards from the cheapest bound, and in each direction
+// the bound only grows, so the search stops as soon as it can't beat the
+// cheapest solution found so far.
+//
+// Ranges can be open on one side, but then the bound must eventually exceed
+// the cost of some solution, to stop the search in that direction. This holds
+// unless solutions can move forever in some direction without their cost
+// growing, which needs some costs to be zero. With more than one coordinate,
+// `MinCost` can't search such a lattice and returns false.
+func (l Lattice) MinCost(cost []int) (x []*big.Int, total *big.Int, ok bool) {
+	if len(cost) != len(l.Origin) {
+		panic("wrong number of costs")
+	}
+
+	for _, c := range cost {
+		if c < 0 {
+			panic("negative cost")
+		}
+	}
+
+	if len(l.Kernel) > 1 && hasFreeRay(l.Kernel, ints(cost)) {
+		return nil, nil, false
+	}
+
+	return minCost(l.Origin, l.Kernel, ints(cost), nil)
+}
+
+// The cheapest non-negative solution, as for `MinCost`, if it costs less than
+// `best` (or at all, if `best` is `nil`).
+func minCost(origin []*big.Int, kernel [][]*big.Int, cost []*big.Int, best *big.Int) (x []*big.Int, total *big.Int, ok bool) {
+	cheaper := func(c *big.Int) bool {
+		return best == nil || c.Cmp(best) < 0
+	}
+
+	if len(kernel) == 0 {
+		for _, o := range origin {
+			if o.Sign() < 0 {
+				return nil, nil, false
+			}
+		}
+
+		if total = dot(cost, origin); !cheaper(total) {
+			return nil, nil, false
+		}
+
+		return origin, total, true
+	}
+
+	// Branch on the coordinate with the narrowest range, by moving it first.
+	var lo, hi *big.Int
+	pick := 0
+	for j := range kernel {
+		l, h, ok := bounds(origin, first(kernel, j))
+		if !ok {
+			return nil, nil, false
+		}
+
+		if j == 0 || narrower(l, h, lo, hi) {
+			lo, hi, pick = l, h, j
+		}
+	}
+
+	kernel = first(kernel, pick)
+
+	if len(kernel) == 1 {
+		// Pick the cheaper end of the range, preferring the lower end when the
+		// cost doesn't change along the line. If that end is unbounded, then the
+		// cost must be constant (because it is non-negative), so any solution
+		// will do.
+		t := lo
+		if dot(cost, kernel[0]).Sign() < 0 || t == nil {
+			t = hi
+		}
+
+		if t == nil {
+			t = new(big.Int)
+		}
+
+		x = step(origin, kernel[0], t)
+		if total = dot(cost, x); !cheaper(total) {
+			return nil, nil, false
+		}
+
+		return x, total, true
+	}
+
+	bound := newCostBound(origin, kernel, cost)
+	try := func(t *big.Int) bool {
+		if !cheaper(bound.ceil(t)) {
+			return false
+		}
+
+		if y, c, ok := minCost(step(origin, kernel[0], t), kernel[1:], cost, best); ok {
+			x, total, best = y, c, c
+		}
+
+		return true
+	}
+
+	mid := bound.argmin(lo, hi)
+	for t := new(big.Int).Set(mid); (hi == nil || t.Cmp(hi) <= 0) && try(t); t = new(big.Int).Add(t, BIG_1) {
+	}
+
+	for t := new(big.Int).Sub(mid, BIG_1); (lo == nil || t.Cmp(lo) >= 0) && try(t); t = new(big.Int).Sub(t, BIG_1) {
+	}
+
+	return x, total, total != nil
+}
+
+// A lower bound on the cost of solutions, as a function of the first
+// coordinate in the kernel basis, `t`, when the other coordinates can take
+// rational values. It is the greatest of a set of lines (and zero, because
+// costs are non-negative), so it is convex.
+//
+// The lines are found by adding the cost, `z`, as an extra coordinate,
+// constrained by `z >= cost · x`, and eliminating every coordinate other than
+// `t` and `z`. What remains are constraints of the form `a t + b z + c >= 0`,
+// and those with `b > 0` bound `z` from below.
+type costBound []constraint
+
+func newCostBound(origin []*big.Int, kernel [][]*big.Int, cost []*big.Int) (lines costBound) {
+	z := len(kernel)
+	cs := make([]constraint, len(origin), len(origin)+1)
+	for i, o := range origin {
+		cs[i] = constraint{make([]*big.Int, z+1), o}
+		for j, k := range kernel {
+			cs[i].coeffs[j] = k[i]
+		}
+		cs[i].coeffs[z] = new(big.Int)
+	}
+
+	// z - cost · (origin + kernel t) >= 0
+	c := constraint{make([]*big.Int, z+1), new(big.Int).Neg(dot(cost, origin))}
+	for j, k := range kernel {
+		c.coeffs[j] = new(big.Int).Neg(dot(cost, k))
+	}
+	c.coeffs[z] = big.NewInt(1)
+	cs = append(cs, c)
+
+	for v := z - 1; v > 0; v-- {
+		cs = eliminate(cs, v)
+	}
+
+	for _, c := range cs {
+		if c.coeffs[z].Sign() > 0 {
+			lines = append(lines, c)
+		}
+	}
+
+	return
+}
+
+// The bound at `t`.
+func (b costBound) at(t *big.Int) *big.Rat {
+	bound := new(big.Rat)
+	for _, l := range b {
+		// a t + b z + c >= 0 means z >= -(a t + c) / b.
+		var n big.Int
+		n.Mul(l.coeffs[0], t)
+		n.Add(&n, l.constant)
+		n.Neg(&n)
+
+		if r := new(big.Rat).SetFrac(&n, l.coeffs[len(l.coeffs)-1]); r.Cmp(bound) > 0 {
+			bound = r
+		}
+	}
+
+	return bound
+}
+
+// The bound at `t`, rounded up, which also bounds the cost of integer
+// solutions, because costs are integers.
+func (b costBound) ceil(t *big.Int) *big.Int {
+	r := b.at(t)
+
+	// Division rounds towards negative infinity, because the denominator is
+	// positive.
+	var q big.Int
+	q.Div(new(big.Int).Neg(r.Num()), r.Denom())
+	return q.Neg(&q)
+}
+
+// The smallest integer in [lo, hi] where the bound is lowest, where a `nil`
+// end leaves the range open on that side. The bound is convex, so it stops
+// decreasing exactly once, which is found by binary search. If the range is
+// open, the search first gallops out from its other end (or zero) to find
+// where to stop, which it will, as long as the bound grows in that direction.
+func (b costBound) argmin(lo, hi *big.Int) *big.Int {
+	rising := func(t *big.Int) bool {
+		return b.at(new(big.Int).Add(t, BIG_1)).Cmp(b.at(t)) >= 0
+	}
+
+	var l, h *big.Int
+	switch {
+	case lo != nil:
+		l = new(big.Int).Set(lo)
+	case hi != nil:
+		l = new(big.Int).Set(hi)
+	default:
+		l = new(big.Int)
+	}
+
+	if hi != nil {
+		h = new(big.Int).Set(hi)
+	} else {
+		h = new(big.Int).Set(l)
+		for s := big.NewInt(1); !rising(h); s.Lsh(s, 1) {
+			h.Add(h, s)
+		}
+	}
+
+	if lo == nil {
+		// The bound is falling just below `l`, so it is lowest after that.
+		for s := big.NewInt(1); rising(l); s.Lsh(s, 1) {
+			l.Sub(l, s)
+		}
+
+		if l.Add(l, BIG_1); l.Cmp(h) > 0 {
+			return h
+		}
+	}
+
+	for l.Cmp(h) < 0 {
+		var m big.Int
+		m.Add(l, h)
+		m.Rsh(&m, 1)
+
+		if rising(&m) {
+			h = &m
+		} else {
+			l = m.Add(&m, BIG_1)
+		}
+	}
+
+	return l
+}
+
+// The range of integer values the first coordinate can take (inclusive),
+// while the other coordinates can take rational values, keeping every
+// component of the solution non-negative. A `nil` bound means the range is
+// unbounded on that side. Returns false if the range is empty.
+func bounds(origin []*big.Int, kernel [][]*big.Int) (lo, hi *big.Int, ok bool) {
+	cs := make([]constraint, len(origin))
+	for i, o := range origin {
+		cs[i] = constraint{make([]*big.Int, len(kernel)), o}
+		for j, k := range kernel {
+			cs[i].coeffs[j] = k[i]
+		}
+	}
+
+	for v := len(kernel) - 1; v > 0; v-- {
+		cs = eliminate(cs, v)
+	}
+
+	for _, c := range cs {
+		a := c.coeffs[0]
+		switch a.Sign() {
+		case 0:
+			if c.constant.Sign() < 0 {
+				return nil, nil, false
+			}
+
+		case 1:
+			// a t + c >= 0 means t >= ceil(-c / a) = -floor(c / a).
+			var b big.Int
+			b.Div(c.constant, a)
+			b.Neg(&b)
+			if lo == nil || b.Cmp(lo) > 0 {
+				lo = &b
+			}
+
+		case -1:
+			// a t + c >= 0 means t <= floor(c / -a).
+			var b, n big.Int
+			b.Div(c.constant, n.Neg(a))
+			if hi == nil || b.Cmp(hi) < 0 {
+				hi = &b
+			}
+		}
+	}
+
+	if lo != nil && hi != nil && lo.Cmp(hi) > 0 {
+		return nil, nil, false
+	}
+
+	return lo, hi, true
+}
+
+// Whether the range `[l, h]` is narrower than `[lo, hi]`, where a `nil` end
+// makes a range infinitely wide.
+func narrower(l, h, lo, hi *big.Int) bool {
+	if l == nil || h == nil {
+		return false
+	}
+
+	if lo == nil || hi == nil {
+		return true
+	}
+
+	var w, width big.Int
+	return w.Sub(h, l).Cmp(width.Sub(hi, lo)) < 0
+}
+
+// A copy of `kernel` with its j-th vector moved to the front.
+func first(kernel [][]*big.Int, j int) [][]*big.Int {
+	k := make([][]*big.Int, 0, len(kernel))
+	k = append(k, kernel[j])
+	k = append(k, kernel[:j]...)
+	return append(k, kernel[j+1:]...)
+}
+
+// Whether there is a direction in the kernel along which solutions can move
+// forever without any of their components becoming negative, and without
+// their cost changing. Such a direction `r` has non-negative components (at
+// least one of which is positive), with `cost · r = 0`. It exists exactly
+// when the constraints left over from eliminating its coordinates in the
+// kernel basis, which only have constants, are all satisfied.
+func hasFreeRay(kernel [][]*big.Int, cost []*big.Int) bool {
+	n := len(kernel)
+	cs := make([]constraint, 0, len(kernel[0])+2)
+	for i := range kernel[0] {
+		c := constraint{make([]*big.Int, n), new(big.Int)}
+		for j, k := range kernel {
+			c.coeffs[j] = k[i]
+		}
+		cs = append(cs, c)
+	}
+
+	// -cost · r >= 0, and the components of r sum to at least one (which is
+	// enough to make one positive, because the direction can be scaled).
+	free := constraint{make([]*big.Int, n), new(big.Int)}
+	some := constraint{make([]*big.Int, n), big.NewInt(-1)}
+	for j, k := range kernel {
+		free.coeffs[j] = new(big.Int).Neg(dot(cost, k))
+		some.coeffs[j] = new(big.Int)
+		for _, e := range k {
+			some.coeffs[j].Add(some.coeffs[j], e)
+		}
+	}
+	cs = append(cs, free, some)
+
+	for v := n - 1; v >= 0; v-- {
+		cs = eliminate(cs, v)
+	}
+
+	for _, c := range cs {
+		if c.constant.Sign() < 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Eliminate coordinate `v` from the constraints, by combining every pair of
+// constraints that bound it from opposite sides, with positive multipliers
+// that cancel it out.
+func eliminate(cs []constraint, v int) (out []constraint) {
+	var pos, neg []constraint
+	for _, c := range cs {
+		switch c.coeffs[v].Sign() {
+		case 0:
+			out = append(out, c)
+		case 1:
+			pos = append(pos, c)
+		case -1:
+			neg = append(neg, c)
+		}
+	}
+
+	for _, p := range pos {
+		for _, n := range neg {
+			var mp, mn big.Int
+			mp.Neg(n.coeffs[v])
+			mn.Set(p.coeffs[v])
+
+			c := constraint{make([]*big.Int, len(p.coeffs)), new(big.Int)}
+			for j := range c.coeffs {
+				c.coeffs[j] = scaled(&mp, p.coeffs[j], &mn, n.coeffs[j])
+			}
+
+			c.constant = scaled(&mp, p.constant, &mn, n.constant)
+			out = append(out, c)
+		}
+	}
+
+	return
+}
+
+// `a x + b y`
+func scaled(a, x, b, y *big.Int) *big.Int {
+	var ax, by big.Int
+	ax.Mul(a, x)
+	by.Mul(b, y)
+	return ax.Add(&ax, &by)
+}
+
+func step(origin, dir []*big.Int, t *big.Int) []*big.Int {
+	x := make([]*big.Int, len(origin))
+	for i := range x {
+		var m big.Int
+		x[i] = new(big.Int).Add(origin[i], m.Mul(t, dir[i]))
+	}
+	return x
+}
+
+func dot(a, b []*big.Int) *big.Int {
+	total := new(big.Int)
+	for i := range a {
+		var m big.Int
+		total.Add(total, m.Mul(a[i], b[i]))
+	}
+	return total
+}
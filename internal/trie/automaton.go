@@ -0,0 +1,121 @@
+package trie
+
+// An Aho-Corasick automaton, for finding every occurrence of every word from
+// a trie in a string, in a single pass over the string.
+//
+// The automaton shares its states with the nodes of the trie it was built
+// from, but replaces the trie's sparse edges with a dense transition table,
+// over an alphabet compressed down to just the bytes that appear in some word.
+// Bytes outside that alphabet cannot be part of any match, so they send the
+// automaton straight back to the root.
+type Automaton struct {
+	// Maps each byte to its index in the compressed alphabet, plus one. Bytes
+	// that do not appear in any word map to zero.
+	symbols [256]int
+	width   int
+
+	// Transitions from state `n` on symbol `c` are found at index
+	// `n * width + c`.
+	delta []int
+
+	depth []int
+	word  []bool
+
+	// For each state, the nearest state representing a proper suffix of it that
+	// is also a word, or -1 if there is none.
+	dict []int
+}
+
+// Build an Aho-Corasick automaton matching the words in `t`. Subsequent
+// modifications to `t` are not reflected in the automaton.
+func (t *Trie) Automaton() *Automaton {
+	a := &Automaton{}
+
+	var alphabet []byte
+	for _, n := range t.nodes {
+		for b := range n.children {
+			if a.symbols[b] == 0 {
+				alphabet = append(alphabet, b)
+				a.symbols[b] = len(alphabet)
+			}
+		}
+	}
+
+	a.width = len(alphabet)
+
+	size := len(t.nodes)
+	a.delta = make([]int, size*a.width)
+	a.depth = make([]int, size)
+	a.word = make([]bool, size)
+	a.dict = make([]int, size)
+	fail := make([]int, size)
+
+	for i, n := range t.nodes {
+		a.depth[i] = n.depth
+		a.word[i] = n.word
+	}
+
+	// Fill in transitions in breadth-first order, so that by the time a state is
+	// visited, the state its failure link points to (which is shallower) is
+	// already complete. Missing edges borrow the transition from the failure
+	// link.
+	a.dict[0] = -1
+	queue := []int{0}
+
+	var curr int
+	for len(queue) > 0 {
+		curr, queue = queue[0], queue[1:]
+		for c, b := range alphabet {
+			if next, ok := t.nodes[curr].children[b]; ok {
+				if curr == 0 {
+					fail[next] = 0
+				} else {
+					fail[next] = a.delta[fail[curr]*a.width+c]
+				}
+
+				if f := fail[next]; a.word[f] {
+					a.dict[next] = f
+				} else {
+					a.dict[next] = a.dict[f]
+				}
+
+				a.delta[curr*a.width+c] = next
+				queue = append(queue, next)
+			} else if curr != 0 {
+				a.delta[curr*a.width+c] = a.delta[fail[curr]*a.width+c]
+			}
+		}
+	}
+
+	return a
+}
+
+// Returns every non-empty occurrence of a word in `s`, as the `start` and
+// `end` (exclusive) byte offsets of the occurrence. Occurrences are produced
+// in order of their end offset, and occurrences sharing an end offset are
+// produced from longest to shortest.
+func (a *Automaton) Matches(s string) func(yield func(start, end int) bool) {
+	return func(yield func(int, int) bool) {
+		state := 0
+		for i := 0; i < len(s); i++ {
+			c := a.symbols[s[i]]
+			if c == 0 {
+				state = 0
+				continue
+			}
+
+			state = a.delta[state*a.width+c-1]
+
+			n := state
+			if !a.word[n] {
+				n = a.dict[n]
+			}
+
+			for ; n > 0; n = a.dict[n] {
+				if !yield(i+1-a.depth[n], i+1) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package trie
+
+// A prefix tree over byte strings. Node 0 is the root, representing the empty
+// string, and every other node represents the string spelled out by the edges
+// leading to it from the root.
+type Trie struct {
+	nodes []node
+	words int
+}
+
+type node struct {
+	children map[byte]int
+	depth    int
+	word     bool
+}
+
+// Create a new trie containing no words.
+func New() *Trie {
+	return &Trie{nodes: []node{{children: make(map[byte]int)}}}
+}
+
+// Create a new trie containing all of `words`.
+func FromWords(words []string) *Trie {
+	t := New()
+	for _, w := range words {
+		t.Insert(w)
+	}
+	return t
+}
+
+// The number of distinct words in the trie.
+func (t *Trie) Len() int {
+	return t.words
+}
+
+// Add `word` to the trie. Returns whether the word was newly added.
+func (t *Trie) Insert(word string) bool {
+	n := 0
+	for i := 0; i < len(word); i++ {
+		next, ok := t.nodes[n].children[word[i]]
+		if !ok {
+			next = len(t.nodes)
+			t.nodes[n].children[word[i]] = next
+			t.nodes = append(t.nodes, node{children: make(map[byte]int), depth: i + 1})
+		}
+		n = next
+	}
+
+	if t.nodes[n].word {
+		return false
+	}
+
+	t.nodes[n].word = true
+	t.words++
+	return true
+}
+
+// Check whether `word` was inserted into the trie.
+func (t *Trie) Contains(word string) bool {
+	n, ok := t.walk(word)
+	return ok && t.nodes[n].word
+}
+
+// Check whether any word in the trie starts with `prefix`.
+func (t *Trie) HasPrefix(prefix string) bool {
+	_, ok := t.walk(prefix)
+	return ok
+}
+
+// Returns the lengths of all words in the trie that are prefixes of `s`, in
+// increasing order.
+func (t *Trie) Prefixes(s string) func(yield func(int) bool) {
+	return func(yield func(int) bool) {
+		n := 0
+		if t.nodes[n].word && !yield(0) {
+			return
+		}
+
+		for i := 0; i < len(s); i++ {
+			next, ok := t.nodes[n].children[s[i]]
+			if !ok {
+				return
+			}
+
+			n = next
+			if t.nodes[n].word && !yield(i+1) {
+				return
+			}
+		}
+	}
+}
+
+// Returns the length of the longest prefix of `s` that is also a prefix of
+// some word in the trie.
+func (t *Trie) LongestPrefix(s string) int {
+	n := 0
+	for i := 0; i < len(s); i++ {
+		next, ok := t.nodes[n].children[s[i]]
+		if !ok {
+			return i
+		}
+		n = next
+	}
+
+	return len(s)
+}
+
+// Follow the edges spelling out `s` from the root, returning the node reached,
+// and whether it exists.
+func (t *Trie) walk(s string) (n int, ok bool) {
+	for i := 0; i < len(s); i++ {
+		if n, ok = t.nodes[n].children[s[i]]; !ok {
+			return 0, false
+		}
+	}
+
+	return n, true
+}
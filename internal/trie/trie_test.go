@@ -0,0 +1,128 @@
+package trie
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestInsertContains(t *testing.T) {
+	tr := FromWords([]string{"r", "wr", "b", "g", "bwu", "rb", "gb", "br"})
+
+	if tr.Len() != 8 {
+		t.Errorf("expected 8 words, got %d", tr.Len())
+	}
+
+	if tr.Insert("wr") {
+		t.Errorf("expected re-inserting 'wr' to be a no-op")
+	}
+
+	for _, w := range []string{"r", "wr", "bwu", "br"} {
+		if !tr.Contains(w) {
+			t.Errorf("expected trie to contain %q", w)
+		}
+	}
+
+	for _, w := range []string{"", "w", "bw", "bwub", "x"} {
+		if tr.Contains(w) {
+			t.Errorf("expected trie not to contain %q", w)
+		}
+	}
+
+	if !tr.HasPrefix("bw") {
+		t.Errorf("expected trie to have prefix 'bw'")
+	}
+
+	if tr.HasPrefix("bb") {
+		t.Errorf("expected trie not to have prefix 'bb'")
+	}
+}
+
+func TestPrefixes(t *testing.T) {
+	tr := FromWords([]string{"b", "bw", "bwu", "bwur", "w"})
+
+	var lens []int
+	for l := range tr.Prefixes("bwugg") {
+		lens = append(lens, l)
+	}
+
+	if !slices.Equal(lens, []int{1, 2, 3}) {
+		t.Errorf("expected prefixes [1 2 3], got %v", lens)
+	}
+}
+
+func TestLongestPrefix(t *testing.T) {
+	tr := FromWords([]string{"bwu", "rb"})
+
+	for s, expect := range map[string]int{
+		"":     0,
+		"g":    0,
+		"bw":   2,
+		"bwg":  2,
+		"bwub": 3,
+		"rbb":  2,
+	} {
+		if actual := tr.LongestPrefix(s); actual != expect {
+			t.Errorf("%q: expected %d, got %d", s, expect, actual)
+		}
+	}
+}
+
+type match struct {
+	start, end int
+}
+
+// Find all matches of `words` in `s`, by checking every position against every
+// word.
+func naiveMatches(words []string, s string) (matches []match) {
+	for end := 1; end <= len(s); end++ {
+		for start := 0; start < end; start++ {
+			if slices.Contains(words, s[start:end]) {
+				matches = append(matches, match{start, end})
+			}
+		}
+	}
+	return
+}
+
+func TestAutomatonMatches(t *testing.T) {
+	for _, tc := range []struct {
+		words []string
+		text  string
+	}{
+		{[]string{"he", "she", "his", "hers"}, "ushers"},
+		{[]string{"a", "aa", "aaa"}, "aaaaa"},
+		{[]string{"r", "wr", "b", "g", "bwu", "rb", "gb", "br"}, "brwrrbggbbwu"},
+		{[]string{"ab", "bc"}, "abxbcab"},
+		{[]string{"abcd", "bc", "c"}, "abcabcd"},
+	} {
+		a := FromWords(tc.words).Automaton()
+
+		var actual []match
+		for start, end := range a.Matches(tc.text) {
+			actual = append(actual, match{start, end})
+		}
+
+		// The naive implementation finds matches in the same order as the
+		// automaton: by end offset, and then from longest to shortest.
+		expect := naiveMatches(tc.words, tc.text)
+		if !slices.Equal(expect, actual) {
+			t.Errorf("%v in %q: expected %v, got %v", tc.words, tc.text, expect, actual)
+		}
+	}
+}
+
+func TestAutomatonEarlyExit(t *testing.T) {
+	a := FromWords([]string{"a"}).Automaton()
+
+	count := 0
+	for range a.Matches(strings.Repeat("a", 10)) {
+		if count++; count == 3 {
+			break
+		}
+	}
+
+	if count != 3 {
+		t.Errorf("expected to stop after 3 matches, got %d", count)
+	}
+}
@@ -0,0 +1,3 @@
+module unionfind
+
+go 1.23.1
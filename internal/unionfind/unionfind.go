@@ -0,0 +1,74 @@
+package unionfind
+
+// A disjoint-set forest over the elements `0` to `n - 1`, using path
+// compression and union by rank, so that a sequence of operations runs in
+// effectively constant amortized time per operation.
+type UnionFind struct {
+	parent []int
+	rank   []uint8
+	sets   int
+}
+
+// Create a new union-find structure with `n` elements, each in its own set.
+func New(n int) *UnionFind {
+	parent := make([]int, n)
+	for i := range parent {
+		parent[i] = i
+	}
+
+	return &UnionFind{parent, make([]uint8, n), n}
+}
+
+// The number of elements tracked by the structure.
+func (u *UnionFind) Len() int {
+	return len(u.parent)
+}
+
+// The number of disjoint sets currently in the structure.
+func (u *UnionFind) Sets() int {
+	return u.sets
+}
+
+// Find the representative of the set containing `x`. Every element visited on
+// the way to the root is re-pointed directly at the root.
+func (u *UnionFind) Find(x int) int {
+	root := x
+	for u.parent[root] != root {
+		root = u.parent[root]
+	}
+
+	for u.parent[x] != root {
+		x, u.parent[x] = u.parent[x], root
+	}
+
+	return root
+}
+
+// Merge the sets containing `x` and `y`. Returns whether the sets were
+// previously disjoint.
+func (u *UnionFind) Union(x, y int) bool {
+	x, y = u.Find(x), u.Find(y)
+	if x == y {
+		return false
+	}
+
+	// Attach the shallower tree under the deeper one, so that trees stay
+	// logarithmic in depth even before path compression kicks in.
+	switch {
+	case u.rank[x] < u.rank[y]:
+		u.parent[x] = y
+	case u.rank[x] > u.rank[y]:
+		u.parent[y] = x
+	default:
+		u.parent[y] = x
+		u.rank[x]++
+	}
+
+	u.sets--
+	return true
+}
+
+// Check whether `x` and `y` belong to the same set.
+func (u *UnionFind) Connected(x, y int) bool {
+	return u.Find(x) == u.Find(y)
+}
@@ -0,0 +1,88 @@
+package unionfind
+
+import (
+	"testing"
+)
+
+func TestSingletons(t *testing.T) {
+	u := New(5)
+
+	if u.Sets() != 5 {
+		t.Errorf("expected 5 sets, got %d", u.Sets())
+	}
+
+	for i := 0; i < 5; i++ {
+		if u.Find(i) != i {
+			t.Errorf("expected %d to be its own root, got %d", i, u.Find(i))
+		}
+	}
+}
+
+func TestUnion(t *testing.T) {
+	u := New(6)
+
+	if !u.Union(0, 1) {
+		t.Errorf("expected union of 0 and 1 to merge sets")
+	}
+
+	if !u.Union(2, 3) {
+		t.Errorf("expected union of 2 and 3 to merge sets")
+	}
+
+	if u.Union(1, 0) {
+		t.Errorf("expected union of 1 and 0 to be a no-op")
+	}
+
+	if u.Sets() != 4 {
+		t.Errorf("expected 4 sets, got %d", u.Sets())
+	}
+
+	if u.Connected(0, 2) {
+		t.Errorf("expected 0 and 2 to be disconnected")
+	}
+
+	u.Union(1, 3)
+	if !u.Connected(0, 2) {
+		t.Errorf("expected 0 and 2 to be connected")
+	}
+
+	if u.Connected(4, 5) {
+		t.Errorf("expected 4 and 5 to be disconnected")
+	}
+
+	if u.Sets() != 3 {
+		t.Errorf("expected 3 sets, got %d", u.Sets())
+	}
+}
+
+func TestPathCompression(t *testing.T) {
+	u := New(100)
+	for i := 1; i < 100; i++ {
+		u.Union(i-1, i)
+	}
+
+	root := u.Find(99)
+	for i := 0; i < 100; i++ {
+		if u.parent[i] != root {
+			u.Find(i)
+		}
+
+		if u.parent[i] != root {
+			t.Errorf("expected %d to point at root %d after Find, got %d", i, root, u.parent[i])
+		}
+	}
+}
+
+func BenchmarkUnionFind(b *testing.B) {
+	const n = 1 << 16
+	for i := 0; i < b.N; i++ {
+		u := New(n)
+		for j := 1; j < n; j++ {
+			u.Union((j*7919)%n, (j*104729)%n)
+		}
+
+		for j := 0; j < n; j++ {
+			u.Find(j)
+		}
+	}
+}